@@ -0,0 +1,69 @@
+package rbm
+
+import (
+  "fmt"
+)
+
+func (self *RBM) NumVisible() int {
+  return self.d
+}
+func (self *RBM) NumHidden() int {
+  return self.m
+}
+
+// Copy of the weights as a d x m matrix, whatever the storage layout.
+func (self *RBM) Weights() [][]float64 {
+  return self.params().W
+}
+// Copies of the visible (a) and hidden (b) biases.
+func (self *RBM) VisibleBiases() []float64 {
+  return append([]float64(nil), self.a...)
+}
+func (self *RBM) HiddenBiases() []float64 {
+  return append([]float64(nil), self.b...)
+}
+
+// Replaces the weights by the d x m matrix w, e.g. to warm-start training.
+// Training state such as momentum and persistent chains is kept.
+func (self *RBM) SetWeights(w [][]float64) error {
+  if len(w) != self.d {
+    return fmt.Errorf("rbm: weights have %d rows, want %d", len(w), self.d)
+  }
+  for i, wi := range w {
+    if len(wi) != self.m {
+      return fmt.Errorf("rbm: weight row %d has length %d, want %d", i, len(wi), self.m)
+    }
+  }
+  for i, wi := range w {
+    for j, x := range wi {
+      self.setWeight(i, j, x)
+    }
+  }
+  return nil
+}
+func (self *RBM) SetVisibleBiases(a []float64) error {
+  if len(a) != self.d {
+    return fmt.Errorf("rbm: %d visible biases, want %d", len(a), self.d)
+  }
+  copy(self.a, a)
+  return nil
+}
+func (self *RBM) SetHiddenBiases(b []float64) error {
+  if len(b) != self.m {
+    return fmt.Errorf("rbm: %d hidden biases, want %d", len(b), self.m)
+  }
+  copy(self.b, b)
+  return nil
+}
+
+// The seed of the model's random source: the one given to WithSeed or
+// drawn by WithRandomSeed, so that a run can be repeated with WithSeed.
+// ok is false for a source set with WithRand or WithSource.
+func (self *RBM) Seed() (seed int64, ok bool) {
+  return self.seed, self.seeded
+}
+
+// Gibbs steps per negative phase chain, see WithCDK.
+func (self *RBM) CDK() int {
+  return self.cdt
+}
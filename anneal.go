@@ -0,0 +1,50 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Temperature of step t (0-based) of an annealed chain of iters steps.
+type Annealing func(t, iters int) float64
+
+// Cools geometrically from T0 at the first step to T1 at the last.
+func GeometricAnnealing(T0, T1 float64) Annealing {
+  return func(t, iters int) float64 {
+    if iters <= 1 {
+      return T1
+    }
+    return T0 * math.Pow(T1 / T0, float64(t) / float64(iters - 1))
+  }
+}
+
+// Cools linearly from T0 at the first step to T1 at the last. Unlike
+// GeometricAnnealing it can end at T1 = 0, making the last steps
+// deterministic.
+func LinearAnnealing(T0, T1 float64) Annealing {
+  return func(t, iters int) float64 {
+    if iters <= 1 {
+      return T1
+    }
+    return T0 + (T1 - T0) * float64(t) / float64(iters - 1)
+  }
+}
+
+// Simulated annealing version of GenerateVisible: the chain starts from noise
+// at a high temperature, where it mixes freely between modes, and cools
+// following schedule (GeometricAnnealing(10, 1) if nil) over iters Gibbs
+// steps. This usually gives cleaner samples than a chain run at T = 1 from
+// the start; a schedule ending at 0 rounds them to the nearest mode.
+func (self *RBM) GenerateVisibleAnnealed(iters int, schedule Annealing) []int {
+  return toInts(self.GenerateVisibleAnnealedFloat(iters, schedule))
+}
+func (self *RBM) GenerateVisibleAnnealedFloat(iters int, schedule Annealing) []float64 {
+  if schedule == nil {
+    schedule = GeometricAnnealing(10, 1)
+  }
+  c := self.NewGibbsChainFloat(nil)
+  for t := 0; t < iters; t++ {
+    c.SetTemperature(schedule(t, iters))
+    c.Step(1)
+  }
+  return c.VisibleFloat()
+}
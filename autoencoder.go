@@ -0,0 +1,264 @@
+package rbm
+
+import (
+  "fmt"
+  "math/rand"
+)
+
+// Deep autoencoder unrolled from a DBN (Hinton & Salakhutdinov, 2006). The
+// encoder computes the hidden expectations of each RBM in turn, up to the
+// code layer of the top one; the decoder mirrors it, layer k mapping back
+// down through the transpose of encoder layer k's weights plus the RBM's
+// visible biases. The weights stay tied in fine-tuning, which therefore
+// updates each weight matrix with the gradients of both of its uses.
+type Autoencoder struct {
+  BackpropSettings
+  layers []*netLayer // encoder, bottom first
+  decB [][]float64  // decoder biases, decB[k] of the inputs of layers[k]
+  velDecB [][]float64
+  out Activation     // Sigmoid for binary data, Linear for Gaussian
+  codeNoise float64  // standard deviation, see SetCodeNoise
+  r *rand.Rand
+}
+
+// Unrolls the stack into an Autoencoder initialized from copies of its
+// weights and biases. Its output is linear if the bottom RBM has Gaussian
+// visible units and sigmoid otherwise. The DBN itself is left unchanged.
+// Panics unless every layer has binary hidden units, as the decoder's are
+// sigmoid.
+func (self *DBN) Unroll() *Autoencoder {
+  for k, layer := range self.layers {
+    if layer.hiddenType != Binary {
+      panic(fmt.Sprintf("rbm: cannot unroll layer %d, its hidden units aren't binary", k))
+    }
+  }
+  ae := &Autoencoder{BackpropSettings: defaultBackprop, out: Sigmoid, r: self.layers[0].r}
+  if self.layers[0].visibleType == Gaussian {
+    ae.out = Linear
+  }
+  for _, layer := range self.layers {
+    ae.layers = append(ae.layers, rbmLayer(layer))
+    ae.decB = append(ae.decB, append([]float64(nil), layer.a...))
+  }
+  ae.velDecB = make([][]float64, len(ae.layers))
+  return ae
+}
+
+func (self *Autoencoder) NumInputs() int {
+  return len(self.layers[0].w)
+}
+
+// Size of the code layer.
+func (self *Autoencoder) CodeSize() int {
+  return len(self.layers[len(self.layers) - 1].b)
+}
+
+// The activations of the encoder for x, x itself first and the code last.
+func (self *Autoencoder) encoder(x []float64) [][]float64 {
+  checkLength("input", len(x), self.NumInputs())
+  a := [][]float64{x}
+  for _, l := range self.layers {
+    a = append(a, l.forward(a[len(a) - 1]))
+  }
+  return a
+}
+
+// The activations of the decoder for code, the reconstruction first and
+// code itself last, so that d[k] has the size of the encoder's a[k].
+func (self *Autoencoder) decoder(code []float64) [][]float64 {
+  L := len(self.layers)
+  d := make([][]float64, L + 1)
+  d[L] = code
+  for k := L - 1; k >= 0; k-- {
+    l := self.layers[k]
+    y := append([]float64(nil), self.decB[k]...)
+    for i, wi := range l.w {
+      for j, cj := range d[k + 1] {
+        y[i] += wi[j] * cj
+      }
+    }
+    act := Sigmoid
+    if k == 0 {
+      act = self.out
+    }
+    if act == Sigmoid {
+      for i := range y {
+        y[i] = expit(y[i])
+      }
+    }
+    d[k] = y
+  }
+  return d
+}
+
+// The code of x, the hidden expectations of the top RBM.
+func (self *Autoencoder) EncodeFloat(x []float64) []float64 {
+  a := self.encoder(x)
+  return a[len(a) - 1]
+}
+func (self *Autoencoder) Encode(x []int) []float64 {
+  return self.EncodeFloat(toFloats(x))
+}
+
+// The reconstruction of the input from code.
+func (self *Autoencoder) Decode(code []float64) []float64 {
+  checkLength("code", len(code), self.CodeSize())
+  return self.decoder(code)[0]
+}
+
+// The reconstruction of x through the code layer.
+func (self *Autoencoder) ReconstructFloat(x []float64) []float64 {
+  return self.Decode(self.EncodeFloat(x))
+}
+func (self *Autoencoder) Reconstruct(x []int) []float64 {
+  return self.ReconstructFloat(toFloats(x))
+}
+
+// Codes of each example, a nonlinear dimensionality reduction of the data.
+func (self *Autoencoder) Transform(vs [][]int) [][]float64 {
+  out := make([][]float64, len(vs))
+  for n, v := range vs {
+    out[n] = self.Encode(v)
+  }
+  return out
+}
+// Same as Transform for real-valued data.
+func (self *Autoencoder) TransformFloat(vs [][]float64) [][]float64 {
+  out := make([][]float64, len(vs))
+  for n, v := range vs {
+    out[n] = self.EncodeFloat(v)
+  }
+  return out
+}
+
+// Backpropagates the reconstruction error of x, accumulating the gradients
+// of the loss into dw (per encoder layer, summed over both uses), db
+// (encoder biases) and dDecB. Returns the loss.
+func (self *Autoencoder) backprop(x []float64, dw [][][]float64, db, dDecB [][]float64) float64 {
+  L := len(self.layers)
+  a := self.encoder(x)
+  if self.codeNoise > 0 {
+    top := self.layers[L - 1]
+    y := top.inputs(a[L - 1])
+    for j := range y {
+      y[j] += self.codeNoise * normal(self.r)
+    }
+    a[L] = top.activate(y)
+  }
+  d := self.decoder(a[L])
+  delta := make([]float64, len(x))
+  for i := range x {
+    delta[i] = d[0][i] - x[i]
+  }
+  loss := outputLoss(self.out, d[0], x)
+  // down the decoder: decoder layer k maps d[k + 1] to d[k]
+  for k := 0; k < L; k++ {
+    w := self.layers[k].w
+    for i, di := range delta {
+      dDecB[k][i] += di
+      if di == 0 {
+        continue
+      }
+      for j, cj := range d[k + 1] {
+        dw[k][i][j] += di * cj
+      }
+    }
+    above := make([]float64, len(d[k + 1]))
+    for j, cj := range d[k + 1] {
+      s := 0.0
+      for i, di := range delta {
+        s += w[i][j] * di
+      }
+      above[j] = s * cj * (1 - cj)
+    }
+    delta = above
+  }
+  // and back down the encoder from the code
+  for k := L - 1; k >= 0; k-- {
+    w, in := self.layers[k].w, a[k]
+    for j, dj := range delta {
+      db[k][j] += dj
+    }
+    for i, xi := range in {
+      if xi == 0 {
+        continue
+      }
+      for j, dj := range delta {
+        dw[k][i][j] += xi * dj
+      }
+    }
+    if k == 0 {
+      break
+    }
+    below := make([]float64, len(in))
+    for i, xi := range in {
+      s := 0.0
+      for j, dj := range delta {
+        s += w[i][j] * dj
+      }
+      below[i] = s * xi * (1 - xi)
+    }
+    delta = below
+  }
+  return loss
+}
+
+// Fine-tunes the whole autoencoder for epochs passes over v in random order,
+// minimizing the reconstruction error: cross-entropy for sigmoid outputs,
+// squared error for linear ones. With verbose set the mean loss of each
+// epoch is logged. Returns an error, without training, if there is no data
+// or an example has the wrong length.
+func (self *Autoencoder) FineTune(v [][]int, epochs int, verbose bool) error {
+  x := make([][]float64, len(v))
+  for n := range v {
+    x[n] = toFloats(v[n])
+  }
+  return self.FineTuneFloat(x, epochs, verbose)
+}
+// Same as FineTune for real-valued data.
+func (self *Autoencoder) FineTuneFloat(x [][]float64, epochs int, verbose bool) error {
+  if err := checkInputs(x, self.NumInputs(), epochs); err != nil {
+    return err
+  }
+  batchSize := self.batchSize()
+  L := len(self.layers)
+  dw, db, dDecB := make([][][]float64, L), make([][]float64, L), make([][]float64, L)
+  for epoch := 0; epoch < epochs; epoch++ {
+    loss := 0.0
+    order := perm(self.r, len(x))
+    for start := 0; start < len(order); start += batchSize {
+      end := start + batchSize
+      if end > len(order) {
+        end = len(order)
+      }
+      for k, l := range self.layers {
+        dw[k], db[k] = zeros(len(l.w), len(l.b)), make([]float64, len(l.b))
+        dDecB[k] = make([]float64, len(self.decB[k]))
+      }
+      for _, n := range order[start:end] {
+        loss += self.backprop(x[n], dw, db, dDecB)
+      }
+      N := float64(end - start)
+      for k, l := range self.layers {
+        self.stepWeights(l.w, dw[k], &l.velW, N)
+        self.stepBiases(l.b, db[k], &l.velB, N)
+        self.stepBiases(self.decB[k], dDecB[k], &self.velDecB[k], N)
+      }
+    }
+    self.logEpoch(verbose, epoch, loss / float64(len(x)))
+  }
+  return nil
+}
+
+// Mean reconstruction loss over the examples, as minimized by FineTune.
+func (self *Autoencoder) Loss(v [][]int) float64 {
+  if len(v) == 0 {
+    return 0
+  }
+  loss := 0.0
+  for _, vn := range v {
+    x := toFloats(vn)
+    loss += outputLoss(self.out, self.ReconstructFloat(x), x)
+  }
+  return loss / float64(len(v))
+}
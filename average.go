@@ -0,0 +1,76 @@
+package rbm
+
+import (
+  "fmt"
+)
+
+// A model whose weights and biases are the means of those of models, e.g.
+// to merge replicas trained on separate data shards. The result is a Clone
+// of the first model with the averaged parameters; training it further
+// takes a new Trainer, whose momentum, optimizer state and chains start
+// afresh. Averaging only makes sense for models that started from the same
+// parameters and haven't drifted far apart, as hidden units of independently
+// initialized models don't correspond. Panics if models is empty or the
+// models differ in shape or unit types.
+func Average(models ...*RBM) *RBM {
+  weights := make([]float64, len(models))
+  for k := range weights {
+    weights[k] = 1
+  }
+  return AverageWeighted(models, weights)
+}
+
+// Same as Average with the models weighted by weights, typically the number
+// of examples each was trained on as in federated averaging. Also panics if
+// the weights don't match the models or don't sum to a positive value.
+func AverageWeighted(models []*RBM, weights []float64) *RBM {
+  if len(models) == 0 {
+    panic("rbm: no models to average")
+  }
+  if len(weights) != len(models) {
+    panic(fmt.Sprintf("rbm: %d weights for %d models", len(weights), len(models)))
+  }
+  first := models[0]
+  total := 0.0
+  for k, m := range models {
+    if m.d != first.d || m.m != first.m {
+      panic(fmt.Sprintf("rbm: cannot average %d x %d and %d x %d models", first.d, first.m, m.d, m.m))
+    }
+    if m.visibleType != first.visibleType || m.hiddenType != first.hiddenType || len(m.softmaxGroups) != len(first.softmaxGroups) {
+      panic(fmt.Sprintf("rbm: model %d has different unit types", k))
+    }
+    total += weights[k]
+  }
+  if !(total > 0) {
+    panic("rbm: averaging weights must sum to a positive value")
+  }
+  p := first.params()
+  for i := range p.A {
+    p.A[i] = 0
+  }
+  for j := range p.B {
+    p.B[j] = 0
+  }
+  for i := range p.W {
+    for j := range p.W[i] {
+      p.W[i][j] = 0
+    }
+  }
+  for k, m := range models {
+    s := weights[k] / total
+    for i := 0; i < m.d; i++ {
+      p.A[i] += s * m.a[i]
+      for j := 0; j < m.m; j++ {
+        p.W[i][j] += s * m.weight(i, j)
+      }
+    }
+    for j := 0; j < m.m; j++ {
+      p.B[j] += s * m.b[j]
+    }
+  }
+  avg := first.Clone()
+  if err := avg.load(p); err != nil {
+    panic(err)
+  }
+  return avg
+}
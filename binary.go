@@ -0,0 +1,204 @@
+package rbm
+
+import (
+  "bufio"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+)
+
+// Binary model format, all values little endian:
+//
+//   magic "RBM\x00", uint32 version
+//   uint32 d, m, cdt, colMajor (0/1), visible unit type, hidden unit type
+//   version 2 only: uint32 binomial trials
+//   uint32 number of softmax groups, then each group as uint32 length and
+//     uint32 unit indices
+//   float64 a (d), b (m), w (d x m, visible unit major)
+var binaryMagic = [4]byte{'R', 'B', 'M', 0}
+
+// Version 2 adds the trials of Binomial visible units; other models are
+// still written as version 1.
+const binaryVersion = 2
+
+type countingWriter struct {
+  w io.Writer
+  n int64
+}
+
+func (self *countingWriter) Write(p []byte) (int, error) {
+  n, err := self.w.Write(p)
+  self.n += int64(n)
+  return n, err
+}
+
+type countingReader struct {
+  r io.Reader
+  n int64
+}
+
+func (self *countingReader) Read(p []byte) (int, error) {
+  n, err := self.r.Read(p)
+  self.n += int64(n)
+  return n, err
+}
+
+// Writes the model structure and parameters in the compact binary format.
+// Implements io.WriterTo.
+func (self *RBM) WriteTo(w io.Writer) (int64, error) {
+  cw := &countingWriter{w: w}
+  bw := bufio.NewWriter(cw)
+  p := self.params()
+  colMajor := uint32(0)
+  if p.ColMajor {
+    colMajor = 1
+  }
+  version := uint32(1)
+  if p.VisibleUnits == Binomial {
+    version = binaryVersion
+  }
+  header := []uint32{version, uint32(p.NumVisible), uint32(p.NumHidden), uint32(p.CDT),
+    colMajor, uint32(p.VisibleUnits), uint32(p.HiddenUnits), uint32(len(p.SoftmaxGroups))}
+  bw.Write(binaryMagic[:])
+  binary.Write(bw, binary.LittleEndian, header)
+  if version == 2 {
+    binary.Write(bw, binary.LittleEndian, uint32(p.Trials))
+  }
+  for _, group := range p.SoftmaxGroups {
+    binary.Write(bw, binary.LittleEndian, uint32(len(group)))
+    for _, i := range group {
+      binary.Write(bw, binary.LittleEndian, uint32(i))
+    }
+  }
+  binary.Write(bw, binary.LittleEndian, p.A)
+  binary.Write(bw, binary.LittleEndian, p.B)
+  for _, wi := range p.W {
+    if err := binary.Write(bw, binary.LittleEndian, wi); err != nil {
+      return cw.n, err
+    }
+  }
+  err := bw.Flush()
+  return cw.n, err
+}
+
+// Reads a model written by WriteTo into self, keeping its random source and
+// its other options. Implements io.ReaderFrom.
+func (self *RBM) ReadFrom(r io.Reader) (int64, error) {
+  // no read-ahead buffering, so r is left positioned right after the model
+  cr := &countingReader{r: r}
+  p, err := readBinaryParams(cr)
+  if err != nil {
+    return cr.n, err
+  }
+  return cr.n, self.load(p)
+}
+
+func readBinaryParams(r io.Reader) (*Params, error) {
+  var magic [4]byte
+  if _, err := io.ReadFull(r, magic[:]); err != nil {
+    return nil, err
+  }
+  if magic != binaryMagic {
+    return nil, errors.New("rbm: not a binary RBM model")
+  }
+  var header [8]uint32
+  if err := binary.Read(r, binary.LittleEndian, header[:]); err != nil {
+    return nil, err
+  }
+  if header[0] < 1 || header[0] > binaryVersion {
+    return nil, fmt.Errorf("rbm: unsupported binary model version %d", header[0])
+  }
+  p := &Params{NumVisible: int(header[1]), NumHidden: int(header[2]), CDT: int(header[3]),
+    ColMajor: header[4] == 1, VisibleUnits: UnitType(header[5]), HiddenUnits: UnitType(header[6])}
+  if p.NumVisible <= 0 || p.NumHidden <= 0 || uint64(header[7]) > uint64(header[1]) {
+    return nil, fmt.Errorf("rbm: corrupt binary model header")
+  }
+  if header[0] == 2 {
+    var trials uint32
+    if err := binary.Read(r, binary.LittleEndian, &trials); err != nil {
+      return nil, err
+    }
+    p.Trials = int(trials)
+  }
+  // the sizes are unchecked until the data behind them have been read, so
+  // everything is allocated as it arrives
+  grouped := uint64(0)
+  for g := 0; g < int(header[7]); g++ {
+    var n uint32
+    if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+      return nil, err
+    }
+    if grouped += uint64(n); grouped > uint64(header[1]) {
+      return nil, fmt.Errorf("rbm: corrupt softmax group")
+    }
+    group32, err := readUint32s(r, int(n))
+    if err != nil {
+      return nil, err
+    }
+    group := make([]int, n)
+    for k, i := range group32 {
+      group[k] = int(i)
+    }
+    p.SoftmaxGroups = append(p.SoftmaxGroups, group)
+  }
+  var err error
+  if p.A, err = readFloat64s(r, p.NumVisible); err != nil {
+    return nil, err
+  }
+  if p.B, err = readFloat64s(r, p.NumHidden); err != nil {
+    return nil, err
+  }
+  for i := 0; i < p.NumVisible; i++ {
+    wi, err := readFloat64s(r, p.NumHidden)
+    if err != nil {
+      return nil, err
+    }
+    p.W = append(p.W, wi)
+  }
+  return p, nil
+}
+
+// Values read per allocation by readFloat64s and readUint32s.
+const readChunk = 1 << 16
+
+// n values, read readChunk at a time so that a corrupt length runs into the
+// end of the data before it is allocated in full.
+func readFloat64s(r io.Reader, n int) ([]float64, error) {
+  var out []float64
+  for len(out) < n {
+    k := n - len(out)
+    if k > readChunk {
+      k = readChunk
+    }
+    out = append(out, make([]float64, k)...)
+    if err := binary.Read(r, binary.LittleEndian, out[len(out) - k:]); err != nil {
+      return nil, corruptIfShort(err)
+    }
+  }
+  return out, nil
+}
+
+// Same as readFloat64s for uint32 values.
+func readUint32s(r io.Reader, n int) ([]uint32, error) {
+  var out []uint32
+  for len(out) < n {
+    k := n - len(out)
+    if k > readChunk {
+      k = readChunk
+    }
+    out = append(out, make([]uint32, k)...)
+    if err := binary.Read(r, binary.LittleEndian, out[len(out) - k:]); err != nil {
+      return nil, corruptIfShort(err)
+    }
+  }
+  return out, nil
+}
+
+// Data ending before the sizes in the header do mean a corrupt model.
+func corruptIfShort(err error) error {
+  if err == io.EOF || err == io.ErrUnexpectedEOF {
+    return errors.New("rbm: corrupt binary model: data shorter than its header says")
+  }
+  return err
+}
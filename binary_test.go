@@ -0,0 +1,52 @@
+package rbm
+
+import (
+  "bytes"
+  "encoding/binary"
+  "testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+  for name, m := range roundTripModels() {
+    var buf bytes.Buffer
+    if _, err := m.WriteTo(&buf); err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    var got RBM
+    if _, err := got.ReadFrom(&buf); err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    sameModel(t, name, &got, m)
+  }
+}
+
+// The binary format goes through the same checks as JSON.
+func TestBinaryDecodeInvalid(t *testing.T) {
+  bad := New(3, 2, WithSeed(1))
+  bad.softmaxGroups = [][]int{{0, 1}, {1, 2}}
+  var buf bytes.Buffer
+  if _, err := bad.WriteTo(&buf); err != nil {
+    t.Fatal(err)
+  }
+  var m RBM
+  if _, err := m.ReadFrom(&buf); err == nil {
+    t.Error("decoded without error")
+  }
+}
+
+// Sizes in a corrupt header are only trusted as far as the data go.
+func TestBinaryCorruptSizes(t *testing.T) {
+  for name, header := range map[string][]uint32{
+    "weights": {1, 1 << 31, 1 << 31, 1, 0, 0, 0, 0},
+    "softmax group": {1, 1 << 31, 1, 1, 0, 0, 0, 1, 1 << 31},
+  } {
+    var buf bytes.Buffer
+    buf.Write(binaryMagic[:])
+    binary.Write(&buf, binary.LittleEndian, header)
+    buf.Write(make([]byte, 64))
+    var m RBM
+    if _, err := m.ReadFrom(&buf); err == nil {
+      t.Errorf("%s: decoded without error", name)
+    }
+  }
+}
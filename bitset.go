@@ -0,0 +1,210 @@
+package rbm
+
+import (
+  "context"
+  "fmt"
+  "math/bits"
+)
+
+// A binary vector packed 64 units to a word, unit i in bit i % 64 of word
+// i / 64: 64 times less memory than []int for large binary datasets, and the
+// hidden layer computations below skip whole words of inactive units. The
+// length is implied by the layer it is used with; bits past it must be 0.
+type Bitset []uint64
+
+// An all-zero Bitset of n units.
+func NewBitset(n int) Bitset {
+  return make(Bitset, (n + 63) / 64)
+}
+
+// v packed into a Bitset; nonzero values count as 1.
+func ToBitset(v []int) Bitset {
+  b := NewBitset(len(v))
+  for i, vi := range v {
+    if vi != 0 {
+      b.Set(i, true)
+    }
+  }
+  return b
+}
+
+// The first n units of self as 0/1 values.
+func (self Bitset) Ints(n int) []int {
+  v := make([]int, n)
+  self.each(func(i int) {
+    v[i] = 1
+  })
+  return v
+}
+
+func (self Bitset) Get(i int) bool {
+  return self[i / 64] & (1 << uint(i % 64)) != 0
+}
+func (self Bitset) Set(i int, on bool) {
+  if on {
+    self[i / 64] |= 1 << uint(i % 64)
+  } else {
+    self[i / 64] &^= 1 << uint(i % 64)
+  }
+}
+
+// Number of units on.
+func (self Bitset) Count() int {
+  n := 0
+  for _, w := range self {
+    n += bits.OnesCount64(w)
+  }
+  return n
+}
+
+// Calls fn on the units that are on, in increasing order.
+func (self Bitset) each(fn func(i int)) {
+  for k, w := range self {
+    for w != 0 {
+      fn(k * 64 + bits.TrailingZeros64(w))
+      w &= w - 1
+    }
+  }
+}
+
+func (self Bitset) floats(n int) []float64 {
+  v := make([]float64, n)
+  self.each(func(i int) {
+    v[i] = 1
+  })
+  return v
+}
+
+func checkBits(what string, b Bitset, n int) {
+  if len(b) != (n + 63) / 64 {
+    panic(fmt.Sprintf("rbm: %s bitset has %d words, want %d", what, len(b), (n + 63) / 64))
+  }
+}
+
+// x + row k of the weight storage dotted with the binary v
+func (self *RBM) dotRowBits(k int, v Bitset, x float64) float64 {
+  if self.w32 != nil {
+    row := self.row32(k)
+    v.each(func(l int) {
+      x += float64(row[l])
+    })
+    return x
+  }
+  row := self.row(k)
+  v.each(func(l int) {
+    x += row[l]
+  })
+  return x
+}
+
+// b + W'v for the binary v
+func (self *RBM) hiddenInputsBits(v Bitset) []float64 {
+  checkBits("visible", v, self.d)
+  x := make([]float64, self.m)
+  if self.colMajor {
+    for j := range x {
+      x[j] = self.dotRowBits(j, v, self.b[j])
+    }
+  } else {
+    copy(x, self.b)
+    v.each(func(i int) {
+      self.addRow(x, i, 1)
+    })
+  }
+  return x
+}
+// a + W h for the binary h
+func (self *RBM) visibleInputsBits(h Bitset) []float64 {
+  checkBits("hidden", h, self.m)
+  x := make([]float64, self.d)
+  if self.colMajor {
+    copy(x, self.a)
+    h.each(func(j int) {
+      self.addRow(x, j, 1)
+    })
+  } else {
+    for i := range x {
+      x[i] = self.dotRowBits(i, h, self.a[i])
+    }
+  }
+  return x
+}
+
+// Same as HiddenLayerExpectation for a packed binary v.
+func (self *RBM) HiddenLayerExpectationBits(v Bitset) []float64 {
+  ps := self.hiddenMeansFrom(self.hiddenInputsBits(v), 1)
+  if self.history != nil {
+    self.recordActivation(append([]float64(nil), ps...))
+  }
+  return ps
+}
+
+// Same as SampleHiddenLayer for a packed binary v, the sample packed too.
+// Panics for NReLU hidden units, whose samples aren't binary.
+func (self *RBM) SampleHiddenLayerBits(v Bitset) Bitset {
+  if self.hiddenType != Binary {
+    panic("rbm: bitset samples need binary hidden units")
+  }
+  h := self.sampleHiddenFrom(self.hiddenInputsBits(v), 1)
+  if self.history != nil {
+    self.recordActivation(append([]float64(nil), h...))
+  }
+  return floatsToBitset(h)
+}
+
+// Same as SampleVisibleLayer for a packed binary h, the sample packed too.
+// Panics unless the visible units are binary.
+func (self *RBM) SampleVisibleLayerBits(h Bitset) Bitset {
+  if self.visibleType != Binary || len(self.softmaxGroups) > 0 {
+    panic("rbm: bitset samples need binary visible units")
+  }
+  x := self.visibleInputsBits(h)
+  for i := range x {
+    x[i] = float64(bernoulli(self.r, expit(x[i])))
+  }
+  return floatsToBitset(x)
+}
+
+// Same as FreeEnergy for a packed binary v.
+func (self *RBM) FreeEnergyBits(v Bitset) float64 {
+  f := 0.0
+  v.each(func(i int) {
+    f -= self.a[i]
+  })
+  for _, x := range self.hiddenInputsBits(v) {
+    f -= softplus(x)
+  }
+  return f
+}
+
+// Same as Train for packed binary examples, each expanded to a dense vector
+// only while it is used, as with TrainSparse.
+func (self *Trainer) TrainBits(v []Bitset, iters int, verbose bool) (History, error) {
+  if err := self.model.checkBitsets(v); err != nil {
+    return History{}, err
+  }
+  return self.train(context.Background(), len(v), func(n int) []float64 { return v[n].floats(self.model.d) }, iters, verbose)
+}
+
+func (self *RBM) checkBitsets(v []Bitset) error {
+  words := (self.d + 63) / 64
+  for n, b := range v {
+    if len(b) != words {
+      return fmt.Errorf("rbm: example %d has %d words, want %d", n, len(b), words)
+    }
+    if self.d % 64 != 0 && b[words - 1] >> uint(self.d % 64) != 0 {
+      return fmt.Errorf("rbm: example %d has units on past %d", n, self.d)
+    }
+  }
+  return nil
+}
+
+func floatsToBitset(x []float64) Bitset {
+  b := NewBitset(len(x))
+  for i, xi := range x {
+    if xi != 0 {
+      b.Set(i, true)
+    }
+  }
+  return b
+}
@@ -0,0 +1,84 @@
+package rbm
+
+// Snapshot of a training run handed to callbacks.
+type TrainingStatus struct {
+  Iteration int                // gradient steps taken so far in this run
+  Epoch int                    // completed epochs, 0 outside TrainEpochs
+  ReconstructionError float64  // mean over the latest mini-batch
+  Model *RBM                   // the model being trained; don't modify it
+}
+
+// Called during training; returning false stops the run early.
+type Callback func(status TrainingStatus) bool
+
+type callback struct {
+  every int
+  fn Callback
+}
+
+// Adds a callback run every `every` training iterations, e.g. for logging,
+// checkpointing or early stopping. Callbacks accumulate; use
+// WithoutCallbacks to drop them.
+func WithCallback(every int, fn Callback) TrainOption {
+  return func(self *Trainer) {
+    if every < 1 {
+      every = 1
+    }
+    self.callbacks = append(self.callbacks, callback{every, fn})
+  }
+}
+
+// Adds a callback run after every epoch of TrainEpochs.
+func WithEpochCallback(fn Callback) TrainOption {
+  return func(self *Trainer) {
+    self.epochCallbacks = append(self.epochCallbacks, fn)
+  }
+}
+
+// Removes all registered callbacks.
+func WithoutCallbacks() TrainOption {
+  return func(self *Trainer) {
+    self.callbacks = nil
+    self.epochCallbacks = nil
+  }
+}
+
+// Runs the callbacks due after iteration it (0-based) on batch; false if one
+// of them asked to stop.
+func (self *Trainer) runCallbacks(it, epoch int, batch [][]float64) bool {
+  status := TrainingStatus{Iteration: it + 1, Epoch: epoch, Model: self.model, ReconstructionError: -1}
+  keepGoing := true
+  for _, cb := range self.callbacks {
+    if (it + 1) % cb.every != 0 {
+      continue
+    }
+    if status.ReconstructionError < 0 {
+      status.ReconstructionError = self.model.batchReconstructionError(batch)
+    }
+    if !cb.fn(status) {
+      keepGoing = false
+    }
+  }
+  return keepGoing
+}
+
+// Runs the epoch callbacks after the given (1-based) epoch.
+func (self *Trainer) runEpochCallbacks(it, epoch int, batch [][]float64) bool {
+  status := TrainingStatus{Iteration: it, Epoch: epoch, Model: self.model}
+  status.ReconstructionError = self.model.batchReconstructionError(batch)
+  keepGoing := true
+  for _, fn := range self.epochCallbacks {
+    if !fn(status) {
+      keepGoing = false
+    }
+  }
+  return keepGoing
+}
+
+func (self *RBM) batchReconstructionError(batch [][]float64) float64 {
+  e := 0.0
+  for _, v := range batch {
+    e += self.ReconstructionErrorFloat(v)
+  }
+  return e / float64(len(batch))
+}
@@ -0,0 +1,62 @@
+package rbm
+
+// Centering trick (Montavon & Müller, 2012; Melchior et al., 2016): the
+// energy is written in terms of v - mu and h - lambda, with the offsets mu
+// and lambda tracking the data means of the units as exponential moving
+// averages with the given rate (0.01 is typical). The model stays an
+// ordinary RBM; only the gradient steps change, which makes CD much less
+// sensitive to the learning rate and initialization.
+func WithCentering(rate float64) TrainOption {
+  return func(self *Trainer) {
+    if rate <= 0 || rate > 1 {
+      self.centering, self.visibleOffset, self.hiddenOffset = 0, nil, nil
+      return
+    }
+    self.centering = rate
+  }
+}
+
+// Turns the averaged gradient (dw, da, db) of the standard parameters into
+// the centered weight gradient dw - mu db' - da lambda', in place, after
+// moving the offsets towards the batch means.
+func (self *Trainer) centerGradient(vs [][]float64, hMean []float64, dw [][]float64, da, db []float64) {
+  model := self.model
+  vMean := make([]float64, model.d)
+  for _, v := range vs {
+    for i, vi := range v {
+      vMean[i] += vi / float64(len(vs))
+    }
+  }
+  if self.visibleOffset == nil {
+    self.visibleOffset = vMean
+    self.hiddenOffset = append([]float64(nil), hMean...)
+  } else {
+    nu := self.centering
+    for i := range vMean {
+      self.visibleOffset[i] = (1 - nu) * self.visibleOffset[i] + nu * vMean[i]
+    }
+    for j := range hMean {
+      self.hiddenOffset[j] = (1 - nu) * self.hiddenOffset[j] + nu * hMean[j]
+    }
+  }
+  mu, lambda := self.visibleOffset, self.hiddenOffset
+  for i := 0; i < model.d; i++ {
+    for j := 0; j < model.m; j++ {
+      dw[i][j] -= mu[i] * db[j] + da[i] * lambda[j]
+    }
+  }
+}
+
+// Maps a step along the centered parameters onto the stored ones: with
+// a = a_c - W lambda and b = b_c - W' mu, a weight change dW also moves the
+// biases by -dW lambda and -dW' mu.
+func (self *Trainer) uncenterGradient(dw [][]float64, da, db []float64) {
+  model := self.model
+  mu, lambda := self.visibleOffset, self.hiddenOffset
+  for i := 0; i < model.d; i++ {
+    for j := 0; j < model.m; j++ {
+      da[i] -= dw[i][j] * lambda[j]
+      db[j] -= dw[i][j] * mu[i]
+    }
+  }
+}
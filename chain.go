@@ -0,0 +1,198 @@
+package rbm
+
+import (
+  "context"
+  "fmt"
+)
+
+// A Gibbs chain of an RBM that can be advanced a few steps at a time,
+// inspected between steps, reset and cloned. The state is always a joint
+// sample (v, h) with h drawn given v. A chain draws from its RBM's random
+// source, so chains of one RBM must not be stepped concurrently.
+type GibbsChain struct {
+  rbm *RBM
+  v, h []float64
+  steps int
+  beta float64      // inverse temperature
+  clamp []bool      // visible units held fixed, if any
+  clamped []float64 // and their values
+}
+
+// Starts a chain at v, or at a random visible vector if v is nil.
+func (self *RBM) NewGibbsChain(v []int) *GibbsChain {
+  c := &GibbsChain{rbm: self, beta: 1}
+  c.Reset(v)
+  return c
+}
+// Same as NewGibbsChain with a real-valued start (Gaussian visibles).
+func (self *RBM) NewGibbsChainFloat(v []float64) *GibbsChain {
+  c := &GibbsChain{rbm: self, beta: 1}
+  c.ResetFloat(v)
+  return c
+}
+
+// Restarts the chain at v, or at a random visible vector if v is nil.
+func (self *GibbsChain) Reset(v []int) {
+  if v == nil {
+    self.ResetFloat(nil)
+  } else {
+    self.ResetFloat(toFloats(v))
+  }
+}
+func (self *GibbsChain) ResetFloat(v []float64) {
+  if v == nil {
+    v = self.rbm.randomVisible()
+  } else {
+    v = append([]float64(nil), v...)
+  }
+  self.v, self.h, self.steps = v, self.sampleHidden(v), 0
+}
+
+// Holds the visible units with clamp[i] true at their current values from
+// now on, so the chain samples the rest conditionally on them; nil releases
+// them. The state stays as it is until the next Step.
+func (self *GibbsChain) SetClamp(clamp []bool) {
+  if clamp == nil {
+    self.clamp, self.clamped = nil, nil
+    return
+  }
+  self.clamp = append([]bool(nil), clamp...)
+  self.clamped = append([]float64(nil), self.v...)
+}
+
+// Advances the chain by n full Gibbs steps h -> v -> h.
+func (self *GibbsChain) Step(n int) {
+  for t := 0; t < n; t++ {
+    self.v = self.rbm.sampleVisibleAt(self.h, self.beta)
+    self.restoreClamped(self.v)
+    self.h = self.sampleHidden(self.v)
+  }
+  self.steps += n
+}
+
+// At T = 1 the hidden samples go through sampleHidden, which also records
+// them in the activation history.
+func (self *GibbsChain) sampleHidden(v []float64) []float64 {
+  if self.beta == 1 {
+    return self.rbm.sampleHidden(v)
+  }
+  return self.rbm.sampleHiddenAt(v, self.beta)
+}
+
+func (self *GibbsChain) restoreClamped(v []float64) {
+  for i, c := range self.clamp {
+    if c {
+      v[i] = self.clamped[i]
+    }
+  }
+}
+
+// Gibbs steps taken since the chain was started or reset.
+func (self *GibbsChain) Steps() int {
+  return self.steps
+}
+
+// Copies of the current state.
+func (self *GibbsChain) Visible() []int {
+  return toInts(self.v)
+}
+func (self *GibbsChain) VisibleFloat() []float64 {
+  return append([]float64(nil), self.v...)
+}
+func (self *GibbsChain) Hidden() []int {
+  return toInts(self.h)
+}
+
+// E[v | h] for the current hidden state, with clamped units at their values.
+func (self *GibbsChain) VisibleMeans() []float64 {
+  p := self.rbm.visibleMeans(self.h)
+  self.restoreClamped(p)
+  return p
+}
+
+// Energy E(v, h) of the current state.
+func (self *GibbsChain) Energy() float64 {
+  return self.rbm.energy(self.v, self.h)
+}
+
+// Advances the chain by one Gibbs step and returns the new visible state,
+// for pulling samples one at a time without a fixed count.
+func (self *GibbsChain) Next() []int {
+  self.Step(1)
+  return self.Visible()
+}
+func (self *GibbsChain) NextFloat() []float64 {
+  self.Step(1)
+  return self.VisibleFloat()
+}
+
+// Streams the visible state every thin Gibbs steps from a goroutine until
+// ctx is done, then closes the channel. The chain mustn't be used otherwise
+// until the channel is closed; cancel ctx and drain the channel to stop
+// early. Panics unless thin is positive.
+func (self *GibbsChain) Samples(ctx context.Context, thin int) <-chan []int {
+  if thin < 1 {
+    panic(fmt.Sprintf("rbm: invalid thinning %d", thin))
+  }
+  out := make(chan []int)
+  go func() {
+    defer close(out)
+    for {
+      self.Step(thin)
+      select {
+      case out <- self.Visible():
+      case <-ctx.Done():
+        return
+      }
+    }
+  }()
+  return out
+}
+
+// An independent chain of the same RBM starting from the current state.
+func (self *GibbsChain) Clone() *GibbsChain {
+  return &GibbsChain{
+    rbm: self.rbm,
+    v: append([]float64(nil), self.v...),
+    h: append([]float64(nil), self.h...),
+    steps: self.steps,
+    beta: self.beta,
+    clamp: self.clamp,
+    clamped: self.clamped,
+  }
+}
+
+// The visible states of a Gibbs chain started at start (random if nil)
+// after each of steps Gibbs steps, to watch the model "dream" and judge how
+// well it mixes.
+func (self *RBM) Daydream(start []int, steps int) [][]int {
+  return self.DaydreamThinned(start, steps, 0, 1)
+}
+// Same as Daydream, but runs burnIn steps first and only keeps every
+// thin-th state after that, so steps states cost burnIn + steps * thin Gibbs
+// steps. Panics if burnIn is negative or thin isn't positive.
+func (self *RBM) DaydreamThinned(start []int, steps, burnIn, thin int) [][]int {
+  dream := self.daydream(self.NewGibbsChain(start), steps, burnIn, thin)
+  out := make([][]int, len(dream))
+  for t, v := range dream {
+    out[t] = toInts(v)
+  }
+  return out
+}
+// Same as DaydreamThinned for real-valued visible units (Gaussian).
+func (self *RBM) DaydreamFloat(start []float64, steps, burnIn, thin int) [][]float64 {
+  return self.daydream(self.NewGibbsChainFloat(start), steps, burnIn, thin)
+}
+
+func (self *RBM) daydream(c *GibbsChain, steps, burnIn, thin int) [][]float64 {
+  if burnIn < 0 || thin < 1 {
+    panic(fmt.Sprintf("rbm: invalid burn-in %d or thinning %d", burnIn, thin))
+  }
+  c.Step(burnIn)
+  dream := make([][]float64, steps)
+  for t := range dream {
+    c.Step(thin)
+    dream[t] = c.VisibleFloat()
+  }
+  return dream
+}
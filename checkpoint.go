@@ -0,0 +1,85 @@
+package rbm
+
+import (
+  "io"
+  "os"
+  "path/filepath"
+)
+
+type checkpointer struct {
+  every int
+  open func(iteration int) (io.WriteCloser, error)
+}
+
+// Saves the model in the binary format (see WriteTo) to path every `every`
+// training iterations. Each snapshot is written to a temporary file in the
+// same directory and renamed over path, so a crash mid-write never leaves a
+// truncated checkpoint. Failures are logged and training carries on.
+func WithCheckpoint(every int, path string) TrainOption {
+  return WithCheckpointWriter(every, func(int) (io.WriteCloser, error) {
+    return newAtomicFile(path)
+  })
+}
+
+// Same as WithCheckpoint, writing each snapshot to the writer returned by
+// open for the iteration number instead; the snapshot is complete once Close
+// returns nil. every < 1 turns checkpointing off.
+func WithCheckpointWriter(every int, open func(iteration int) (io.WriteCloser, error)) TrainOption {
+  return func(self *Trainer) {
+    if every < 1 || open == nil {
+      self.checkpoint = nil
+      return
+    }
+    self.checkpoint = &checkpointer{every, open}
+  }
+}
+
+func (self *Trainer) saveCheckpoint(iteration int) {
+  w, err := self.checkpoint.open(iteration)
+  if err == nil {
+    _, err = self.model.WriteTo(w)
+    if cerr := w.Close(); err == nil {
+      err = cerr
+    }
+  }
+  if err != nil {
+    self.logf("Checkpoint at iteration %d failed: %v\n", iteration, err)
+  }
+}
+
+// Temporary file that replaces path on a successful Close.
+type atomicFile struct {
+  *os.File
+  path string
+  failed bool
+}
+
+func newAtomicFile(path string) (*atomicFile, error) {
+  f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path) + ".tmp*")
+  if err != nil {
+    return nil, err
+  }
+  return &atomicFile{File: f, path: path}, nil
+}
+
+func (self *atomicFile) Write(p []byte) (int, error) {
+  n, err := self.File.Write(p)
+  if err != nil {
+    self.failed = true
+  }
+  return n, err
+}
+
+func (self *atomicFile) Close() error {
+  err := self.File.Sync()
+  if cerr := self.File.Close(); err == nil {
+    err = cerr
+  }
+  if err == nil && !self.failed {
+    err = os.Rename(self.Name(), self.path)
+  }
+  if err != nil || self.failed {
+    os.Remove(self.Name())
+  }
+  return err
+}
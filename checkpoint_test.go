@@ -0,0 +1,52 @@
+package rbm
+
+import (
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+func TestCheckpointWritten(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "model.rbm")
+  data := [][]int{{1, 0, 1, 0}, {0, 1, 0, 1}}
+  tr := NewTrainer(New(4, 3, WithSeed(1)), WithCheckpoint(5, path))
+  if _, err := tr.Train(data, 10, false); err != nil {
+    t.Fatal(err)
+  }
+  f, err := os.Open(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer f.Close()
+  var got RBM
+  if _, err := got.ReadFrom(f); err != nil {
+    t.Fatal(err)
+  }
+  sameModel(t, "checkpoint", &got, tr.Model())
+  if names, _ := filepath.Glob(filepath.Join(dir, "*")); len(names) != 1 {
+    t.Errorf("files left behind: %v", names)
+  }
+}
+
+// A failed snapshot leaves the previous checkpoint in place.
+func TestCheckpointAtomic(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "model.rbm")
+  if err := os.WriteFile(path, []byte("previous"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  f, err := newAtomicFile(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  f.Write([]byte("trunc"))
+  f.failed = true
+  f.Close()
+  if data, err := os.ReadFile(path); err != nil || string(data) != "previous" {
+    t.Errorf("checkpoint now %q (%v)", data, err)
+  }
+  if names, _ := filepath.Glob(filepath.Join(dir, "*")); len(names) != 1 {
+    t.Errorf("files left behind: %v", names)
+  }
+}
@@ -0,0 +1,255 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+)
+
+// Training objective of a ClassRBM (Larochelle & Bengio, 2008).
+type Objective int
+
+const (
+  Generative     Objective = iota // contrastive divergence on the joint p(v, y)
+  Discriminative                  // exact gradient of log p(y | v)
+  Hybrid                          // Discriminative plus alpha times Generative
+)
+
+// Classification RBM: an RBM whose visible layer is the features followed by
+// a one-of-K softmax group of label units. Labels are predicted by comparing
+// the free energies F(v, y) of the K completions of v.
+type ClassRBM struct {
+  rbm *RBM
+  trainer *Trainer
+  d int // features
+  k int // classes
+  objective Objective
+  alpha float64
+}
+
+// numFeatures visible units plus numClasses label units; the other arguments
+// are as for NewRBM. Trains with the Hybrid objective and alpha = 0.01 unless
+// changed with SetObjective.
+func NewClassRBM(numFeatures, numClasses, numHidden, cdt int, r *rand.Rand, opts ...Option) *ClassRBM {
+  if numClasses < 2 {
+    panic(fmt.Sprintf("rbm: a ClassRBM needs at least two classes, got %d", numClasses))
+  }
+  labels := make([]int, numClasses)
+  for y := range labels {
+    labels[y] = numFeatures + y
+  }
+  opts = append([]Option{WithSoftmaxGroups([][]int{labels})}, opts...)
+  self := &ClassRBM{
+    rbm: NewRBM(numFeatures + numClasses, numHidden, cdt, r, opts...),
+    d: numFeatures,
+    k: numClasses,
+    objective: Hybrid,
+    alpha: 0.01,
+  }
+  self.trainer = NewTrainer(self.rbm)
+  return self
+}
+
+// The underlying joint RBM over features and label units.
+func (self *ClassRBM) RBM() *RBM {
+  return self.rbm
+}
+
+// The trainer of the joint RBM. Its options (learning rate and schedule,
+// batch size, optimizer or momentum, regularization, negative phase,
+// sampler) govern Train.
+func (self *ClassRBM) Trainer() *Trainer {
+  return self.trainer
+}
+
+// alpha weighs the generative gradient of the Hybrid objective.
+func (self *ClassRBM) SetObjective(obj Objective, alpha float64) {
+  self.objective, self.alpha = obj, alpha
+}
+
+// the features v followed by the one-hot encoding of label y (none if y < 0)
+func (self *ClassRBM) joint(v []float64, y int) []float64 {
+  x := make([]float64, self.d + self.k)
+  copy(x, v)
+  if y >= 0 {
+    x[self.d + y] = 1
+  }
+  return x
+}
+
+// Hidden inputs o[y] with label y switched on, and the unnormalized log
+// p(y | v) = -F(v, y) + a.v of each label.
+func (self *ClassRBM) labelScores(v []float64) (o [][]float64, scores []float64) {
+  base := self.rbm.hiddenInputs(self.joint(v, -1))
+  o = make([][]float64, self.k)
+  scores = make([]float64, self.k)
+  for y := 0; y < self.k; y++ {
+    u := self.d + y
+    o[y] = make([]float64, self.rbm.m)
+    scores[y] = self.rbm.a[u]
+    for j, x := range base {
+      o[y][j] = x + self.rbm.weight(u, j)
+      scores[y] += softplus(o[y][j])
+    }
+  }
+  return
+}
+
+// p(y | v) for every label y.
+func (self *ClassRBM) PredictProba(v []int) []float64 {
+  _, scores := self.labelScores(toFloats(v))
+  return normalizeLog(scores)
+}
+
+// The most probable label of v.
+func (self *ClassRBM) Predict(v []int) int {
+  _, scores := self.labelScores(toFloats(v))
+  best := 0
+  for y, s := range scores {
+    if s > scores[best] {
+      best = y
+    }
+  }
+  return best
+}
+
+// Fraction of the examples whose label is predicted correctly.
+func (self *ClassRBM) Accuracy(v [][]int, labels []int) float64 {
+  if len(v) == 0 {
+    return 0
+  }
+  correct := 0
+  for n := range v {
+    if self.Predict(v[n]) == labels[n] {
+      correct++
+    }
+  }
+  return float64(correct) / float64(len(v))
+}
+
+// exp(s) / sum(exp(s)), computed stably
+func normalizeLog(s []float64) []float64 {
+  max := math.Inf(-1)
+  for _, x := range s {
+    max = math.Max(max, x)
+  }
+  p := make([]float64, len(s))
+  sum := 0.0
+  for y, x := range s {
+    p[y] = math.Exp(x - max)
+    sum += p[y]
+  }
+  for y := range p {
+    p[y] /= sum
+  }
+  return p
+}
+
+// Gradient of log p(y | v), accumulated into dw, da and db.
+func (self *ClassRBM) discriminativeGradient(v []float64, y int, dw [][]float64, da, db []float64) {
+  o, scores := self.labelScores(v)
+  p := normalizeLog(scores)
+  for c := 0; c < self.k; c++ {
+    u := self.d + c
+    target := 0.0
+    if c == y {
+      target = 1
+    }
+    da[u] += target - p[c]
+    for j, x := range o[c] {
+      g := (target - p[c]) * expit(x)
+      db[j] += g
+      dw[u][j] += g
+      for i := 0; i < self.d; i++ {
+        if v[i] != 0 {
+          dw[i][j] += v[i] * g
+        }
+      }
+    }
+  }
+}
+
+// Trains on the labelled examples for iters mini-batches (of the trainer's
+// batch size) with the configured objective. With verbose set the training
+// accuracy on (up to) the first 100 examples is logged at the trainer's log
+// interval. The trainer's sparsity and weight decay terms apply once to the
+// combined update, whatever the objective. Returns an error, without
+// training, if there is no data, an example doesn't have numFeatures values
+// or a label is out of range.
+func (self *ClassRBM) Train(v [][]int, labels []int, iters int, verbose bool) error {
+  rbm, tr := self.rbm, self.trainer
+  N := len(v)
+  if len(labels) != N {
+    return fmt.Errorf("rbm: %d labels for %d examples", len(labels), N)
+  }
+  for n := range v {
+    if len(v[n]) != self.d {
+      return fmt.Errorf("rbm: example %d has length %d, want %d", n, len(v[n]), self.d)
+    }
+    if labels[n] < 0 || labels[n] >= self.k {
+      return fmt.Errorf("rbm: label %d of example %d out of range [0, %d)", labels[n], n, self.k)
+    }
+  }
+  if err := checkTrainingSize(N, iters); err != nil {
+    return err
+  }
+  if err := tr.startSampler(N); err != nil {
+    return err
+  }
+  for it := 0; it < iters; it++ {
+    if verbose && (it + 1) % tr.logInterval() == 0 {
+      M := N
+      if M > 100 {
+        M = 100
+      }
+      tr.logf("Training iteration: %d, accuracy: %.4f\n", it + 1, self.Accuracy(v[:M], labels[:M]))
+    }
+    batch := make([][]float64, tr.batchSize)
+    ys := make([]int, tr.batchSize)
+    for k := range batch {
+      n := tr.drawExample(N)
+      batch[k], ys[k] = toFloats(v[n]), labels[n]
+    }
+    // the hybrid objective is a single update along the discriminative
+    // gradient plus alpha times the generative one
+    dw := zeros(rbm.d, rbm.m)
+    da, db := make([]float64, rbm.d), make([]float64, rbm.m)
+    if self.objective != Generative {
+      for k := range batch {
+        self.discriminativeGradient(batch[k], ys[k], dw, da, db)
+      }
+      dw, da, db = scaleGradient(dw, da, db, 1 / float64(len(batch)))
+    }
+    joint := make([][]float64, len(batch))
+    for k := range batch {
+      joint[k] = self.joint(batch[k], ys[k])
+    }
+    var hMean []float64
+    if self.objective != Discriminative {
+      alpha := 1.0
+      if self.objective == Hybrid {
+        alpha = self.alpha
+      }
+      var gw [][]float64
+      var ga, gb []float64
+      gw, ga, gb, hMean = tr.averageGradient(joint, nil)
+      if tr.centering != 0 {
+        tr.centerGradient(joint, hMean, gw, ga, gb)
+        tr.uncenterGradient(gw, ga, gb)
+      }
+      addScaled(dw, gw, alpha)
+      addScaled([][]float64{da, db}, [][]float64{ga, gb}, alpha)
+    } else {
+      hMean = make([]float64, rbm.m)
+      for _, h := range rbm.hiddenMeansBatch(joint) {
+        for j := range h {
+          hMean[j] += h[j] / float64(len(joint))
+        }
+      }
+    }
+    tr.penalize(joint, hMean, dw, db)
+    tr.applyGradient(tr.learningRate(), dw, da, db)
+    tr.steps++
+  }
+  return nil
+}
@@ -0,0 +1,37 @@
+package rbm
+
+import (
+  "math"
+  "testing"
+)
+
+func weightNorm(m *RBM) float64 {
+  sum := 0.0
+  for _, row := range m.Weights() {
+    for _, x := range row {
+      sum += x * x
+    }
+  }
+  return math.Sqrt(sum)
+}
+
+// Weight decay shrinks the weights whatever the objective.
+func TestClassRBMWeightDecay(t *testing.T) {
+  v := [][]int{{1, 1, 0, 0}, {0, 0, 1, 1}, {1, 0, 1, 0}}
+  labels := []int{0, 1, 0}
+  for _, objective := range []Objective{Generative, Discriminative, Hybrid} {
+    norms := make([]float64, 2)
+    for k, decay := range []float64{0, 0.5} {
+      c := NewClassRBM(4, 2, 3, 1, nil, WithSeed(1), WithWeightInit(GaussianInit))
+      c.SetObjective(objective, 0.1)
+      c.Trainer().SetOptions(WithWeightDecay(decay))
+      if err := c.Train(v, labels, 20, false); err != nil {
+        t.Fatal(err)
+      }
+      norms[k] = weightNorm(c.RBM())
+    }
+    if !(norms[1] < norms[0]) {
+      t.Errorf("objective %d: weight norm %v with decay, %v without", objective, norms[1], norms[0])
+    }
+  }
+}
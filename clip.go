@@ -0,0 +1,44 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Rescales each gradient step whose L2 norm over all parameters exceeds
+// maxNorm down to that norm, keeping its direction (default 0, off). Guards
+// against the blow-ups of high learning rates and Gaussian visible units.
+func WithGradientNormClipping(maxNorm float64) TrainOption {
+  return func(self *Trainer) {
+    self.clipNorm = math.Max(maxNorm, 0)
+  }
+}
+
+// Clips every entry of each gradient step to [-limit, limit] (default 0,
+// off). Applied before norm clipping when both are set.
+func WithGradientClipping(limit float64) TrainOption {
+  return func(self *Trainer) {
+    self.clipValue = math.Max(limit, 0)
+  }
+}
+
+// Clips the gradient in place according to the options above.
+func (self *Trainer) clipGradient(dw [][]float64, da, db []float64) {
+  if c := self.clipValue; c > 0 {
+    eachGradient(dw, da, db, func(k int, g float64) float64 {
+      return math.Max(-c, math.Min(c, g))
+    })
+  }
+  if self.clipNorm > 0 {
+    sq := 0.0
+    eachGradient(dw, da, db, func(k int, g float64) float64 {
+      sq += g * g
+      return g
+    })
+    if norm := math.Sqrt(sq); norm > self.clipNorm {
+      scale := self.clipNorm / norm
+      eachGradient(dw, da, db, func(k int, g float64) float64 {
+        return scale * g
+      })
+    }
+  }
+}
@@ -0,0 +1,130 @@
+package rbm
+
+import (
+  "math/rand"
+)
+
+// A deep copy of the model, parameters and structure, so that training the
+// copy leaves self alone and vice versa. A model sampling from a Source (the
+// default, or WithSeed) gets a copy of it, continuing the same random stream
+// independently; otherwise the copy shares self's *rand.Rand, so give it its
+// own with WithRand or WithSource before using both concurrently.
+func (self *RBM) Clone() *RBM {
+  c := *self
+  c.w = copyVector(self.w)
+  if self.w32 != nil {
+    c.w32 = append([]float32(nil), self.w32...)
+  }
+  c.a, c.b = copyVector(self.a), copyVector(self.b)
+  c.softmaxGroups = copyGroups(self.softmaxGroups)
+  if self.groupOf != nil {
+    c.groupOf = append([]int(nil), self.groupOf...)
+  }
+  if self.src != nil {
+    src := *self.src
+    c.src = &src
+    c.r = rand.New(c.src)
+  }
+  c.dropMask = nil
+  c.history = nil
+  if self.history != nil {
+    c.SetActivationHistory(len(self.history.buf))
+  }
+  if self.scoreCalibration != nil {
+    sc := *self.scoreCalibration
+    c.scoreCalibration = &sc
+  }
+  return &c
+}
+
+// A deep copy of the trainer training a Clone of its model: configuration
+// and training state (momentum or optimizer state, PCD and tempering chains,
+// sparsity and centering estimates) are copied, so that training the copy
+// leaves self and its model alone and vice versa. The package's Samplers are
+// copied with their position in the epoch. Callbacks, the logger and the
+// progress reporter are shared, early stopping starts afresh and
+// checkpointing is dropped, as two trainers writing the same file would
+// clobber each other. Optimizers, NegativePhases and Samplers other than the
+// package's own are shared too.
+func (self *Trainer) Clone() *Trainer {
+  self.sync()
+  c := *self
+  c.model = self.model.Clone()
+  c.velW, c.velA, c.velB = copyMatrix(self.velW), copyVector(self.velA), copyVector(self.velB)
+  c.optimizer = cloneOptimizer(self.optimizer)
+  c.phase = clonePhase(self.phase)
+  c.sampler = cloneSampler(self.sampler)
+  c.hiddenActivity = copyVector(self.hiddenActivity)
+  c.visibleOffset, c.hiddenOffset = copyVector(self.visibleOffset), copyVector(self.hiddenOffset)
+  c.callbacks = append([]callback(nil), self.callbacks...)
+  c.epochCallbacks = append([]Callback(nil), self.epochCallbacks...)
+  if self.earlyStop != nil {
+    es := *self.earlyStop
+    es.begin()
+    c.earlyStop = &es
+  }
+  c.checkpoint = nil
+  c.lastV, c.lastHDelta = nil, nil
+  if self.rates != nil {
+    rates := *self.rates
+    c.rates = &rates
+  }
+  return &c
+}
+
+// Replaces the parameters and structure of self by copies of other's,
+// keeping self's random source and configuration. The trainers of self drop
+// the state tied to the old parameters (momentum, optimizer state, chains)
+// at their next step, as when loading a model.
+func (self *RBM) CopyFrom(other *RBM) {
+  if err := self.load(other.params()); err != nil {
+    // other's own parameters always validate
+    panic(err)
+  }
+}
+
+func cloneOptimizer(opt Optimizer) Optimizer {
+  switch o := opt.(type) {
+  case *SGD:
+    return &SGD{}
+  case *Momentum:
+    return &Momentum{mu: o.mu, v: copyVector(o.v)}
+  case *AdaGrad:
+    return &AdaGrad{g2: copyVector(o.g2)}
+  case *RMSProp:
+    return &RMSProp{decay: o.decay, g2: copyVector(o.g2)}
+  case *Adam:
+    return &Adam{beta1: o.beta1, beta2: o.beta2, t: o.t, m: copyVector(o.m), v: copyVector(o.v)}
+  }
+  return opt
+}
+
+// copies that keep nil as nil
+func copyVector(x []float64) []float64 {
+  if x == nil {
+    return nil
+  }
+  return append([]float64(nil), x...)
+}
+
+func copyMatrix(x [][]float64) [][]float64 {
+  if x == nil {
+    return nil
+  }
+  c := make([][]float64, len(x))
+  for k, row := range x {
+    c[k] = copyVector(row)
+  }
+  return c
+}
+
+func copyGroups(groups [][]int) [][]int {
+  if groups == nil {
+    return nil
+  }
+  c := make([][]int, len(groups))
+  for g, group := range groups {
+    c[g] = append([]int(nil), group...)
+  }
+  return c
+}
@@ -0,0 +1,287 @@
+// Command rbm trains RBMs and uses them for feature extraction and sampling
+// without writing Go.
+//
+//   rbm train -data train.csv -hidden 500 -epochs 20 -model model.json
+//   rbm transform -model model.json -data test.csv -out features.csv
+//   rbm sample -model model.json -n 10 -iters 1000 -out samples.csv
+//   rbm filters -model model.json -width 28 -height 28 -out filters.png
+//
+// Data files are read by extension: .csv (one example per row, binarized at
+// -threshold unless -gaussian), .svm/.libsvm (libsvm format) and IDX image
+// files such as train-images-idx3-ubyte[.gz]. Models ending in .json are
+// stored as JSON, anything else in the compact binary format.
+package main
+
+import (
+  "bufio"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "io"
+  "math/rand"
+  "os"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/aotimme/rbm"
+  "github.com/aotimme/rbm/dataset"
+)
+
+func main() {
+  if len(os.Args) < 2 {
+    usage()
+  }
+  var err error
+  switch os.Args[1] {
+  case "train":
+    err = train(os.Args[2:])
+  case "transform":
+    err = transform(os.Args[2:])
+  case "sample":
+    err = sample(os.Args[2:])
+  case "filters":
+    err = filters(os.Args[2:])
+  default:
+    usage()
+  }
+  if err != nil {
+    fmt.Fprintln(os.Stderr, "rbm:", err)
+    os.Exit(1)
+  }
+}
+
+func usage() {
+  fmt.Fprintln(os.Stderr, "usage: rbm train|transform|sample|filters [flags]; rbm <command> -h for flags")
+  os.Exit(2)
+}
+
+// Reads the examples in path as real values.
+func loadData(path string) ([][]float64, error) {
+  switch {
+  case strings.HasSuffix(path, ".csv"):
+    return rbm.LoadCSVFloat(path)
+  case strings.HasSuffix(path, ".svm"), strings.HasSuffix(path, ".libsvm"):
+    x, _, err := rbm.LoadLibSVM(path, 0)
+    return x, err
+  case strings.Contains(path, "idx"):
+    return dataset.LoadImages(path)
+  }
+  return nil, fmt.Errorf("can't tell the format of %s", path)
+}
+
+func loadModel(path string) (*rbm.RBM, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  var m rbm.RBM
+  if strings.HasSuffix(path, ".json") {
+    err = json.NewDecoder(f).Decode(&m)
+  } else {
+    _, err = m.ReadFrom(bufio.NewReader(f))
+  }
+  return &m, err
+}
+
+func saveModel(m *rbm.RBM, path string) error {
+  f, err := os.Create(path)
+  if err != nil {
+    return err
+  }
+  if strings.HasSuffix(path, ".json") {
+    err = json.NewEncoder(f).Encode(m)
+  } else {
+    _, err = m.WriteTo(f)
+  }
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  return err
+}
+
+// stdout for "-" or an empty path, else the created file
+func createOutput(path string) (io.WriteCloser, error) {
+  if path == "" || path == "-" {
+    return os.Stdout, nil
+  }
+  return os.Create(path)
+}
+
+func writeCSV(w io.Writer, rows [][]float64) error {
+  bw := bufio.NewWriter(w)
+  for _, row := range rows {
+    for i, x := range row {
+      if i > 0 {
+        bw.WriteByte(',')
+      }
+      bw.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+    }
+    bw.WriteByte('\n')
+  }
+  return bw.Flush()
+}
+
+func newRand(seed int64) *rand.Rand {
+  if seed == 0 {
+    seed = time.Now().UnixNano()
+  }
+  return rand.New(rand.NewSource(seed))
+}
+
+func train(args []string) error {
+  fs := flag.NewFlagSet("train", flag.ExitOnError)
+  data := fs.String("data", "", "training data file")
+  modelPath := fs.String("model", "model.json", "where to save the model")
+  hidden := fs.Int("hidden", 100, "number of hidden units")
+  cdt := fs.Int("cdt", 1, "contrastive divergence steps")
+  epochs := fs.Int("epochs", 10, "passes over the data")
+  iters := fs.Int("iters", 0, "train for this many random mini-batches instead of -epochs")
+  lr := fs.Float64("lr", 0.05, "learning rate")
+  batch := fs.Int("batch", 10, "mini-batch size")
+  momentum := fs.Float64("momentum", 0, "momentum")
+  optimizer := fs.String("optimizer", "sgd", "update rule: sgd, adagrad, rmsprop or adam")
+  decay := fs.Float64("decay", 0, "L2 weight decay")
+  pcd := fs.Int("pcd", 0, "persistent CD with this many fantasy particles")
+  threshold := fs.Float64("threshold", 0.5, "binarization threshold")
+  gaussian := fs.Bool("gaussian", false, "Gaussian visible units on the raw values")
+  workers := fs.Int("workers", 1, "goroutines per gradient step")
+  seed := fs.Int64("seed", 0, "random seed (0 for the clock)")
+  verbose := fs.Bool("v", false, "log progress")
+  fs.Parse(args)
+  if *data == "" {
+    return fmt.Errorf("train: -data is required")
+  }
+  x, err := loadData(*data)
+  if err != nil {
+    return err
+  }
+  if len(x) == 0 {
+    return fmt.Errorf("train: %s holds no examples", *data)
+  }
+  if *hidden < 1 || *cdt < 1 {
+    return fmt.Errorf("train: -hidden and -cdt must be positive")
+  }
+  opts := []rbm.Option{
+    rbm.WithCDK(*cdt), rbm.WithRand(newRand(*seed)),
+    rbm.WithWeightInit(rbm.GaussianInit), rbm.WithWorkers(*workers),
+  }
+  topts := []rbm.TrainOption{
+    rbm.WithLearningRate(*lr), rbm.WithBatchSize(*batch),
+    rbm.WithMomentum(*momentum), rbm.WithWeightDecay(*decay),
+  }
+  switch *optimizer {
+  case "sgd":
+  case "adagrad":
+    topts = append(topts, rbm.WithOptimizer(rbm.NewAdaGrad()))
+  case "rmsprop":
+    topts = append(topts, rbm.WithOptimizer(rbm.NewRMSProp(0.9)))
+  case "adam":
+    topts = append(topts, rbm.WithOptimizer(rbm.NewAdam(0.9, 0.999)))
+  default:
+    return fmt.Errorf("train: unknown optimizer %q", *optimizer)
+  }
+  if *pcd > 0 {
+    topts = append(topts, rbm.WithPCD(*pcd))
+  }
+  if *gaussian {
+    opts = append(opts, rbm.WithVisibleUnits(rbm.Gaussian))
+  }
+  m := rbm.New(len(x[0]), *hidden, opts...)
+  t := rbm.NewTrainer(m, topts...)
+  if *gaussian {
+    m.InitFromDataFloat(x)
+    if *iters > 0 {
+      _, err = t.TrainFloat(x, *iters, *verbose)
+    } else {
+      _, err = t.TrainEpochsFloat(x, *epochs, *verbose)
+    }
+  } else {
+    v := rbm.Binarize(x, *threshold)
+    m.InitFromData(v)
+    if *iters > 0 {
+      _, err = t.Train(v, *iters, *verbose)
+    } else {
+      _, err = t.TrainEpochs(v, *epochs, *verbose)
+    }
+  }
+  if err != nil {
+    return err
+  }
+  return saveModel(m, *modelPath)
+}
+
+func transform(args []string) error {
+  fs := flag.NewFlagSet("transform", flag.ExitOnError)
+  data := fs.String("data", "", "data file")
+  modelPath := fs.String("model", "model.json", "trained model")
+  out := fs.String("out", "-", "CSV file for the hidden expectations")
+  threshold := fs.Float64("threshold", 0.5, "binarization threshold")
+  gaussian := fs.Bool("gaussian", false, "feed the raw values (Gaussian visible units)")
+  fs.Parse(args)
+  m, err := loadModel(*modelPath)
+  if err != nil {
+    return err
+  }
+  x, err := loadData(*data)
+  if err != nil {
+    return err
+  }
+  for n, xn := range x {
+    if len(xn) != m.NumVisible() {
+      return fmt.Errorf("transform: example %d has %d values, the model %d visible units", n, len(xn), m.NumVisible())
+    }
+  }
+  var features [][]float64
+  if *gaussian {
+    features = m.TransformFloat(x)
+  } else {
+    features = m.Transform(rbm.Binarize(x, *threshold))
+  }
+  w, err := createOutput(*out)
+  if err != nil {
+    return err
+  }
+  defer w.Close()
+  return writeCSV(w, features)
+}
+
+func sample(args []string) error {
+  fs := flag.NewFlagSet("sample", flag.ExitOnError)
+  modelPath := fs.String("model", "model.json", "trained model")
+  n := fs.Int("n", 10, "number of samples")
+  iters := fs.Int("iters", 1000, "Gibbs steps per sample")
+  out := fs.String("out", "-", "CSV file for the samples")
+  seed := fs.Int64("seed", 0, "random seed (0 for the clock)")
+  fs.Parse(args)
+  m, err := loadModel(*modelPath)
+  if err != nil {
+    return err
+  }
+  m.SetOptions(rbm.WithRand(newRand(*seed)))
+  samples := make([][]float64, *n)
+  for k := range samples {
+    samples[k] = m.GenerateVisibleFloat(*iters)
+  }
+  w, err := createOutput(*out)
+  if err != nil {
+    return err
+  }
+  defer w.Close()
+  return writeCSV(w, samples)
+}
+
+func filters(args []string) error {
+  fs := flag.NewFlagSet("filters", flag.ExitOnError)
+  modelPath := fs.String("model", "model.json", "trained model")
+  width := fs.Int("width", 28, "image width in pixels")
+  height := fs.Int("height", 28, "image height in pixels")
+  out := fs.String("out", "filters.png", "PNG file for the filter grid")
+  fs.Parse(args)
+  m, err := loadModel(*modelPath)
+  if err != nil {
+    return err
+  }
+  return m.SaveFilters(*out, *width, *height)
+}
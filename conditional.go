@@ -0,0 +1,62 @@
+package rbm
+
+// Conditional Gibbs sampling: runs iters Gibbs cycles from v, resampling only
+// the visible units whose clamp entry is false. Clamped units keep their
+// value from v, so the result is a sample of the free units given them.
+func (self *RBM) SampleClamped(v []int, clamp []bool, iters int) []int {
+  return toInts(self.SampleClampedFloat(toFloats(v), clamp, iters))
+}
+// Same as SampleClamped for real-valued visible data (Gaussian units).
+func (self *RBM) SampleClampedFloat(v []float64, clamp []bool, iters int) []float64 {
+  c := self.NewGibbsChainFloat(v)
+  c.SetClamp(clamp)
+  c.Step(iters)
+  return c.VisibleFloat()
+}
+
+// Fills in the visible units of v whose known entry is false, e.g. the
+// missing pixels of an image: they start from a random visible vector and are
+// Gibbs sampled for iters cycles with the known units clamped. Returns v with
+// the unknown units replaced by their probabilities (means for Gaussian
+// units) given the final hidden state, which is smoother than a single
+// sample.
+func (self *RBM) Inpaint(v []int, known []bool, iters int) []float64 {
+  start := self.randomVisible()
+  for i, k := range known {
+    if k {
+      start[i] = float64(v[i])
+    }
+  }
+  c := self.NewGibbsChainFloat(start)
+  c.SetClamp(known)
+  c.Step(iters)
+  return c.VisibleMeans()
+}
+
+// Multiple imputation of the units flagged in missingMask. Observed values in
+// v are binarized at 0.5 and clamped; for each of the numImputations runs the
+// missing units start from fair coin flips and are Gibbs sampled for
+// gibbsIters cycles. Returns one complete visible vector per run. Panics
+// unless v and missingMask have one entry per visible unit.
+func (self *RBM) ImputeMissing(v []float64, missingMask []bool, numImputations, gibbsIters int) [][]int {
+  checkLength("visible", len(v), self.d)
+  checkLength("mask", len(missingMask), self.d)
+  observed := make([]bool, self.d)
+  start := make([]int, self.d)
+  for i := 0; i < self.d; i++ {
+    observed[i] = !missingMask[i]
+    if observed[i] && v[i] >= 0.5 {
+      start[i] = 1
+    }
+  }
+  imputations := make([][]int, numImputations)
+  for k := 0; k < numImputations; k++ {
+    for i := 0; i < self.d; i++ {
+      if !observed[i] {
+        start[i] = bernoulli(self.r, 0.5)
+      }
+    }
+    imputations[k] = self.SampleClamped(start, observed, gibbsIters)
+  }
+  return imputations
+}
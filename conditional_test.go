@@ -0,0 +1,35 @@
+package rbm
+
+import (
+  "testing"
+)
+
+// Units 0 and 1 are coupled through the single hidden unit, so a missing
+// unit 1 is imputed as a copy of unit 0.
+func TestImputeMissing(t *testing.T) {
+  m := New(4, 1, WithSeed(4))
+  m.setWeight(0, 0, 10)
+  m.setWeight(1, 0, 10)
+  m.a[0], m.a[1], m.b[0] = -5, -5, -10
+  mask := []bool{false, true, false, true}
+  for _, x := range []int{0, 1} {
+    v := []float64{float64(x), 0.3, 0.8, 0.7}
+    imputations := m.ImputeMissing(v, mask, 200, 10)
+    if len(imputations) != 200 {
+      t.Fatalf("%d imputations, want 200", len(imputations))
+    }
+    copies := 0
+    for _, u := range imputations {
+      if u[0] != x || u[2] != 1 {
+        t.Fatalf("observed units changed: %v from %v", u, v)
+      }
+      if u[1] == x {
+        copies++
+      }
+    }
+    if copies < 180 {
+      t.Errorf("unit 0 = %d: unit 1 imputed as a copy %d times in 200", x, copies)
+    }
+  }
+  mustPanic(t, "short mask", func() { m.ImputeMissing([]float64{0, 1, 0, 1}, mask[:3], 1, 1) })
+}
@@ -0,0 +1,418 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+)
+
+// Convolutional RBM with probabilistic max-pooling (Lee, Grosse, Ranganath &
+// Ng, 2009). The visible layer is a width x height image (row-major, like
+// the flattened images the fully-connected model takes) and hidden unit
+// (k, y, x) sees the filterSize x filterSize patch at (x, y) through the
+// filter k shared by all positions, so the model is translation invariant
+// and has numFilters * filterSize^2 weights whatever the image size. Each
+// hidden map is divided into pool x pool blocks of which at most one unit is
+// on; a block's pooling unit is on if any of them is, and the pooling units
+// are the features passed up (Transform). Visible units are binary, or
+// Gaussian with WithVisibleUnits(Gaussian) for standardized real pixels.
+type ConvRBM struct {
+  rbm *RBM // filters as filterSize^2 x numFilters weights, hidden biases b
+  trainer *Trainer
+  width, height int
+  filterSize, pool int
+}
+
+// The arguments after pool are as for NewRBM. Panics unless the filters fit
+// the image and pool divides the size of the hidden maps,
+// (width - filterSize + 1) x (height - filterSize + 1).
+func NewConvRBM(width, height, filterSize, numFilters, pool, cdt int, r *rand.Rand, opts ...Option) *ConvRBM {
+  if filterSize < 1 || filterSize > width || filterSize > height {
+    panic(fmt.Sprintf("rbm: %d x %d filters do not fit %d x %d images", filterSize, filterSize, width, height))
+  }
+  if pool < 1 || (width - filterSize + 1) % pool != 0 || (height - filterSize + 1) % pool != 0 {
+    panic(fmt.Sprintf("rbm: pooling size %d does not divide the %d x %d hidden maps",
+      pool, width - filterSize + 1, height - filterSize + 1))
+  }
+  self := &ConvRBM{
+    rbm: NewRBM(filterSize * filterSize, numFilters, cdt, r, opts...),
+    width: width,
+    height: height,
+    filterSize: filterSize,
+    pool: pool,
+  }
+  self.trainer = NewTrainer(self.rbm)
+  return self
+}
+
+// The filters as an RBM with one visible unit per filter pixel and one
+// hidden unit per filter, e.g. RBM().SaveFilters(path, filterSize,
+// filterSize) renders them. Its visible biases all hold the bias shared by
+// the image pixels, so saving the RBM saves the whole model.
+func (self *ConvRBM) RBM() *RBM {
+  return self.rbm
+}
+
+// The trainer of the filters. Its options (learning rate, batch size,
+// momentum or optimizer, schedule, weight decay, sparsity) govern training.
+func (self *ConvRBM) Trainer() *Trainer {
+  return self.trainer
+}
+
+func (self *ConvRBM) NumFilters() int {
+  return self.rbm.m
+}
+
+// Size of each hidden map.
+func (self *ConvRBM) hiddenSize() (w, h int) {
+  return self.width - self.filterSize + 1, self.height - self.filterSize + 1
+}
+
+// Size of each pooling map.
+func (self *ConvRBM) PoolSize() (w, h int) {
+  hw, hh := self.hiddenSize()
+  return hw / self.pool, hh / self.pool
+}
+
+// Inputs b_k + (W_k * v)(x, y) of every hidden unit, one map per filter.
+func (self *ConvRBM) hiddenInputs(v []float64) [][]float64 {
+  checkLength("image", len(v), self.width * self.height)
+  hw, hh := self.hiddenSize()
+  F := self.filterSize
+  x := make([][]float64, self.rbm.m)
+  for k := range x {
+    x[k] = make([]float64, hw * hh)
+    for y := 0; y < hh; y++ {
+      for xx := 0; xx < hw; xx++ {
+        s := self.rbm.b[k]
+        for dy := 0; dy < F; dy++ {
+          row := (y + dy) * self.width + xx
+          for dx := 0; dx < F; dx++ {
+            if vi := v[row + dx]; vi != 0 {
+              s += self.rbm.weight(dy * F + dx, k) * vi
+            }
+          }
+        }
+        x[k][y * hw + xx] = s
+      }
+    }
+  }
+  return x
+}
+
+// Calls fn with the hidden units of each pooling block of a map, as indices
+// into the map.
+func (self *ConvRBM) eachBlock(fn func(block int, units []int)) {
+  hw, _ := self.hiddenSize()
+  pw, ph := self.PoolSize()
+  units := make([]int, self.pool * self.pool)
+  for by := 0; by < ph; by++ {
+    for bx := 0; bx < pw; bx++ {
+      for dy := 0; dy < self.pool; dy++ {
+        for dx := 0; dx < self.pool; dx++ {
+          units[dy * self.pool + dx] = (by * self.pool + dy) * hw + bx * self.pool + dx
+        }
+      }
+      fn(by * pw + bx, units)
+    }
+  }
+}
+
+// Replaces the hidden inputs x by p(h = 1 | v) under probabilistic
+// max-pooling, exp(x_i) / (1 + sum_block exp(x)), and returns the pooling
+// probabilities 1 - 1 / (1 + sum_block exp(x)).
+func (self *ConvRBM) poolMeans(x [][]float64) [][]float64 {
+  pw, ph := self.PoolSize()
+  pooled := make([][]float64, len(x))
+  for k, xk := range x {
+    pooled[k] = make([]float64, pw * ph)
+    self.eachBlock(func(block int, units []int) {
+      max := 0.0 // the "all off" state has input 0
+      for _, u := range units {
+        max = math.Max(max, xk[u])
+      }
+      off := math.Exp(-max)
+      sum := off
+      for _, u := range units {
+        xk[u] = math.Exp(xk[u] - max)
+        sum += xk[u]
+      }
+      for _, u := range units {
+        xk[u] /= sum
+      }
+      pooled[k][block] = 1 - off / sum
+    })
+  }
+  return pooled
+}
+
+// Hidden and pooling probabilities given v.
+func (self *ConvRBM) hiddenMeans(v []float64) (h, pooled [][]float64) {
+  h = self.hiddenInputs(v)
+  pooled = self.poolMeans(h)
+  return
+}
+
+// A sample from the hidden probabilities p: at most one unit per block on.
+func (self *ConvRBM) sampleHidden(p [][]float64) [][]float64 {
+  h := make([][]float64, len(p))
+  for k, pk := range p {
+    h[k] = make([]float64, len(pk))
+    self.eachBlock(func(_ int, units []int) {
+      u := uniform(self.rbm.r)
+      for _, i := range units {
+        u -= pk[i]
+        if u < 0 {
+          h[k][i] = 1
+          break
+        }
+      }
+    })
+  }
+  return h
+}
+
+// The visible bias shared by all pixels, kept in every visible bias of the
+// RBM; they are updated together, so the mean is the common value.
+func (self *ConvRBM) visibleBias() float64 {
+  c := 0.0
+  for _, ai := range self.rbm.a {
+    c += ai
+  }
+  return c / float64(len(self.rbm.a))
+}
+
+// E[v | h]: the shared bias plus the sum of the filters placed at the active
+// hidden units, through a sigmoid for binary visibles.
+func (self *ConvRBM) visibleMeans(h [][]float64) []float64 {
+  hw, hh := self.hiddenSize()
+  F := self.filterSize
+  v := make([]float64, self.width * self.height)
+  c := self.visibleBias()
+  for i := range v {
+    v[i] = c
+  }
+  for k, hk := range h {
+    for y := 0; y < hh; y++ {
+      for x := 0; x < hw; x++ {
+        hj := hk[y * hw + x]
+        if hj == 0 {
+          continue
+        }
+        for dy := 0; dy < F; dy++ {
+          row := (y + dy) * self.width + x
+          for dx := 0; dx < F; dx++ {
+            v[row + dx] += hj * self.rbm.weight(dy * F + dx, k)
+          }
+        }
+      }
+    }
+  }
+  if self.rbm.visibleType != Gaussian {
+    for i := range v {
+      v[i] = expit(v[i])
+    }
+  }
+  return v
+}
+
+func (self *ConvRBM) sampleVisible(h [][]float64) []float64 {
+  v := self.visibleMeans(h)
+  for i := range v {
+    if self.rbm.visibleType == Gaussian {
+      v[i] += normal(self.rbm.r)
+    } else {
+      v[i] = float64(bernoulli(self.rbm.r, v[i]))
+    }
+  }
+  return v
+}
+
+// Adds sign times the sufficient statistics of (v, h) to the gradients.
+func (self *ConvRBM) addStatistics(v []float64, h [][]float64, sign float64, dw [][]float64, db []float64, dc *float64) {
+  hw, hh := self.hiddenSize()
+  F := self.filterSize
+  for k, hk := range h {
+    for y := 0; y < hh; y++ {
+      for x := 0; x < hw; x++ {
+        hj := hk[y * hw + x]
+        if hj == 0 {
+          continue
+        }
+        db[k] += sign * hj
+        for dy := 0; dy < F; dy++ {
+          row := (y + dy) * self.width + x
+          for dx := 0; dx < F; dx++ {
+            dw[dy * F + dx][k] += sign * hj * v[row + dx]
+          }
+        }
+      }
+    }
+  }
+  for _, vi := range v {
+    *dc += sign * vi
+  }
+}
+
+// Trains with CD on iters mini-batches of images drawn at random from v,
+// each width * height pixels. Gradients are averaged over the positions of a
+// hidden map, so the learning rate does not depend on the image size. The
+// trainer's sparsity settings (WithSparsity) push each filter's mean hidden
+// activation towards the target through its bias, as Lee et al. do. With
+// verbose set the reconstruction error of the batch is logged at the
+// trainer's log interval. Returns an error, without training, if there is no data or
+// an image has the wrong size.
+func (self *ConvRBM) Train(v [][]int, iters int, verbose bool) error {
+  data := make([][]float64, len(v))
+  for n := range v {
+    data[n] = toFloats(v[n])
+  }
+  return self.TrainFloat(data, iters, verbose)
+}
+// Same as Train for real-valued images (Gaussian visibles).
+func (self *ConvRBM) TrainFloat(v [][]float64, iters int, verbose bool) error {
+  rbm, tr := self.rbm, self.trainer
+  hw, hh := self.hiddenSize()
+  for n := range v {
+    if len(v[n]) != self.width * self.height {
+      return fmt.Errorf("rbm: image %d has %d pixels, want %d x %d", n, len(v[n]), self.width, self.height)
+    }
+  }
+  if err := checkTrainingSize(len(v), iters); err != nil {
+    return err
+  }
+  if err := tr.startSampler(len(v)); err != nil {
+    return err
+  }
+  for it := 0; it < iters; it++ {
+    tr.sync()
+    dw := zeros(rbm.d, rbm.m)
+    da, db := make([]float64, rbm.d), make([]float64, rbm.m)
+    dc := 0.0
+    activity := make([]float64, rbm.m)
+    batch := make([][]float64, tr.batchSize)
+    for n := range batch {
+      batch[n] = v[tr.drawExample(len(v))]
+      h0, _ := self.hiddenMeans(batch[n])
+      self.addStatistics(batch[n], h0, 1, dw, db, &dc)
+      for k, hk := range h0 {
+        for _, hj := range hk {
+          activity[k] += hj
+        }
+      }
+      vk, hk := batch[n], h0
+      for t := 0; t < rbm.cdt; t++ {
+        vk = self.sampleVisible(self.sampleHidden(hk))
+        hk, _ = self.hiddenMeans(vk)
+      }
+      self.addStatistics(vk, hk, -1, dw, db, &dc)
+    }
+    // per hidden position (weights, biases) or pixel (visible bias)
+    positions := float64(hw * hh)
+    for i := range dw {
+      for k := range dw[i] {
+        dw[i][k] /= positions
+      }
+    }
+    for k := range db {
+      db[k] /= positions
+    }
+    // the shared bias gets the same update in every copy
+    dc /= float64(self.width * self.height)
+    for i := range da {
+      da[i] = dc
+    }
+    if tr.sparsityCost != 0 {
+      for k := range activity {
+        activity[k] /= positions * float64(tr.batchSize)
+      }
+      if tr.hiddenActivity == nil {
+        tr.hiddenActivity = activity
+      } else {
+        for k := range activity {
+          tr.hiddenActivity[k] = tr.sparsityDecay * tr.hiddenActivity[k] + (1 - tr.sparsityDecay) * activity[k]
+        }
+      }
+      for k := range db {
+        db[k] += float64(tr.batchSize) * tr.sparsityCost * (tr.sparsityTarget - tr.hiddenActivity[k])
+      }
+    }
+    if tr.weightDecay != 0 {
+      for i := 0; i < rbm.d; i++ {
+        for k := 0; k < rbm.m; k++ {
+          dw[i][k] -= tr.weightDecay * float64(tr.batchSize) * rbm.weight(i, k)
+        }
+      }
+    }
+    eps := tr.learningRate() / float64(tr.batchSize)
+    tr.applyGradient(eps, dw, da, db)
+    tr.steps++
+    if verbose && (it + 1) % tr.logInterval() == 0 {
+      tr.logf("Training iteration: %d, reconstruction error: %.4f\n", it + 1, self.reconstructionError(batch))
+    }
+  }
+  return nil
+}
+
+// Mean squared error of the one-step mean-field reconstructions of vs.
+func (self *ConvRBM) reconstructionError(vs [][]float64) float64 {
+  e := 0.0
+  for _, v := range vs {
+    for i, x := range self.Reconstruct(v) {
+      e += (v[i] - x) * (v[i] - x)
+    }
+  }
+  return e / float64(len(vs))
+}
+
+// E[v | E[h | v]], the mean-field reconstruction of the image v.
+func (self *ConvRBM) Reconstruct(v []float64) []float64 {
+  h, _ := self.hiddenMeans(v)
+  return self.visibleMeans(h)
+}
+
+// Pooling probabilities of each image, numFilters maps of PoolSize each
+// concatenated into one feature vector (map major, then row-major), e.g. the
+// input of a classifier or of the next layer.
+func (self *ConvRBM) Transform(vs [][]int) [][]float64 {
+  out := make([][]float64, len(vs))
+  for n, v := range vs {
+    out[n] = self.pooledFeatures(toFloats(v))
+  }
+  return out
+}
+// Same as Transform for real-valued images.
+func (self *ConvRBM) TransformFloat(vs [][]float64) [][]float64 {
+  out := make([][]float64, len(vs))
+  for n, v := range vs {
+    out[n] = self.pooledFeatures(v)
+  }
+  return out
+}
+
+func (self *ConvRBM) pooledFeatures(v []float64) []float64 {
+  _, pooled := self.hiddenMeans(v)
+  var f []float64
+  for _, pk := range pooled {
+    f = append(f, pk...)
+  }
+  return f
+}
+
+// The mean E[v | h] of the last of iters block Gibbs steps from a random
+// image.
+func (self *ConvRBM) Generate(iters int) []float64 {
+  v := make([]float64, self.width * self.height)
+  for i := range v {
+    if self.rbm.visibleType == Gaussian {
+      v[i] = normal(self.rbm.r)
+    } else {
+      v[i] = float64(bernoulli(self.rbm.r, 0.5))
+    }
+  }
+  h, _ := self.hiddenMeans(v)
+  for it := 1; it < iters; it++ {
+    h, _ = self.hiddenMeans(self.sampleVisible(self.sampleHidden(h)))
+  }
+  return self.visibleMeans(self.sampleHidden(h))
+}
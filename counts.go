@@ -0,0 +1,123 @@
+package rbm
+
+import (
+  "math"
+  "math/rand"
+)
+
+// Count-valued visible units, for word or event counts that would otherwise
+// have to be thresholded to binary. With WithVisibleUnits(Poisson) unit i is
+// Poisson with rate exp(input); with WithVisibleUnits(Binomial) it counts the
+// successes of n trials (WithBinomialTrials) of probability sigmoid(input).
+// Either way the hidden layer sees the raw counts, so the []int methods take
+// and return counts directly. Inputs are typically scaled down (a smaller
+// learning rate, Gaussian weight initialization) as large counts make the
+// hidden inputs large.
+
+// Number of trials n of Binomial visible units (default 1, i.e. binary).
+func WithBinomialTrials(n int) Option {
+  return func(self *RBM) {
+    if n < 1 {
+      n = 1
+    }
+    self.trials = n
+  }
+}
+
+func (self *RBM) binomialTrials() int {
+  if self.trials < 1 {
+    return 1
+  }
+  return self.trials
+}
+
+func isCount(t UnitType) bool {
+  return t == Poisson || t == Binomial
+}
+
+// log of the base measure of count v: -log v! for Poisson units, log C(n, v)
+// for Binomial ones. The energy is -a.v - log h(v) - b.h - v'Wh.
+func (self *RBM) logBaseMeasure(v float64) float64 {
+  lv, _ := math.Lgamma(v + 1)
+  if self.visibleType == Poisson {
+    return -lv
+  }
+  n := float64(self.binomialTrials())
+  ln, _ := math.Lgamma(n + 1)
+  lnv, _ := math.Lgamma(n - v + 1)
+  return ln - lv - lnv
+}
+
+// Mean count given the (temperature scaled) input x.
+func (self *RBM) countMean(x float64) float64 {
+  if self.visibleType == Poisson {
+    return math.Exp(x)
+  }
+  return float64(self.binomialTrials()) * expit(x)
+}
+
+// Sample given the mean count.
+func (self *RBM) sampleCount(mean float64) float64 {
+  if self.visibleType == Poisson {
+    return poisson(self.r, mean)
+  }
+  n := self.binomialTrials()
+  return binomial(self.r, n, mean / float64(n))
+}
+
+// Most probable count given the mean count.
+func (self *RBM) countMode(mean float64) float64 {
+  if self.visibleType == Poisson {
+    return math.Floor(mean)
+  }
+  n := float64(self.binomialTrials())
+  return math.Min(math.Floor((n + 1) * mean / n), n)
+}
+
+// Poisson sample: multiplication of uniforms for small rates, Hörmann's
+// transformed rejection (PTRS) otherwise.
+func poisson(r *rand.Rand, lambda float64) float64 {
+  if lambda <= 0 {
+    return 0
+  }
+  if lambda < 30 {
+    limit := math.Exp(-lambda)
+    k, p := 0.0, uniform(r)
+    for p > limit {
+      k++
+      p *= uniform(r)
+    }
+    return k
+  }
+  slam, loglam := math.Sqrt(lambda), math.Log(lambda)
+  b := 0.931 + 2.53 * slam
+  a := -0.059 + 0.02483 * b
+  invAlpha := 1.1239 + 1.1328 / (b - 3.4)
+  vr := 0.9277 - 3.6224 / (b - 2)
+  for {
+    u := uniform(r) - 0.5
+    v := uniform(r)
+    us := 0.5 - math.Abs(u)
+    k := math.Floor((2 * a / us + b) * u + lambda + 0.43)
+    if us >= 0.07 && v <= vr {
+      return k
+    }
+    if k < 0 || (us < 0.013 && v > us) {
+      continue
+    }
+    lk, _ := math.Lgamma(k + 1)
+    if math.Log(v) + math.Log(invAlpha) - math.Log(a / (us * us) + b) <= -lambda + k * loglam - lk {
+      return k
+    }
+  }
+}
+
+// Binomial sample as a sum of n Bernoulli draws, fine for the modest trial
+// counts of count data.
+func binomial(r *rand.Rand, n int, p float64) float64 {
+  k := 0
+  for t := 0; t < n; t++ {
+    k += bernoulli(r, p)
+  }
+  return float64(k)
+}
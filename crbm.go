@@ -0,0 +1,226 @@
+package rbm
+
+import (
+  "fmt"
+  "math/rand"
+)
+
+// Conditional RBM for sequences (Taylor, Hinton & Roweis, 2007): the visible
+// and hidden biases at time t are a + A u and b + B u, where u concatenates
+// the order previous frames, most recent first. Usually used with Gaussian
+// visibles (WithVisibleUnits(Gaussian)) on standardized real-valued frames.
+type CRBM struct {
+  rbm *RBM
+  trainer *Trainer
+  order int
+  A [][]float64 // autoregressive visible weights (d x order*d)
+  B [][]float64 // past-to-hidden weights (m x order*d)
+  velA, velB [][]float64 // momentum velocities of A and B
+}
+
+// The arguments other than order are as for NewRBM.
+func NewCRBM(numVisible, numHidden, order, cdt int, r *rand.Rand, opts ...Option) *CRBM {
+  if order < 1 {
+    panic(fmt.Sprintf("rbm: CRBM order must be at least 1, got %d", order))
+  }
+  self := &CRBM{rbm: NewRBM(numVisible, numHidden, cdt, r, opts...), order: order}
+  self.trainer = NewTrainer(self.rbm)
+  self.A = make([][]float64, numVisible)
+  for i := range self.A {
+    self.A[i] = make([]float64, order * numVisible)
+  }
+  self.B = make([][]float64, numHidden)
+  for j := range self.B {
+    self.B[j] = make([]float64, order * numVisible)
+  }
+  return self
+}
+
+// The static part of the model: w and the bias offsets a and b.
+func (self *CRBM) RBM() *RBM {
+  return self.rbm
+}
+
+// The trainer of the static part. Its options (learning rate, batch size,
+// momentum or optimizer, schedule, weight decay, clipping) govern CRBM
+// training. A and B follow the learning rate, schedule, weight decay and
+// momentum only: with an optimizer they still take plain (or momentum) SGD
+// steps, and they are neither clipped nor rate-scaled.
+func (self *CRBM) Trainer() *Trainer {
+  return self.trainer
+}
+
+// Number of past frames each frame is conditioned on.
+func (self *CRBM) Order() int {
+  return self.order
+}
+
+// The history vector u of the frame following past, which must hold at least
+// order frames.
+func (self *CRBM) history(past [][]float64) []float64 {
+  d := self.rbm.d
+  u := make([]float64, self.order * d)
+  for k := 0; k < self.order; k++ {
+    copy(u[k * d:(k + 1) * d], past[len(past) - 1 - k])
+  }
+  return u
+}
+
+// A view of the RBM with the dynamic biases for history u. It shares w but
+// always samples its negative phase with CD.
+func (self *CRBM) conditioned(u []float64) *RBM {
+  cond := *self.rbm
+  cond.a = append([]float64(nil), self.rbm.a...)
+  cond.b = append([]float64(nil), self.rbm.b...)
+  for i, Ai := range self.A {
+    for l, ul := range u {
+      cond.a[i] += Ai[l] * ul
+    }
+  }
+  for j, Bj := range self.B {
+    for l, ul := range u {
+      cond.b[j] += Bj[l] * ul
+    }
+  }
+  return &cond
+}
+
+// Trains for iters mini-batches of frames drawn at random from the positions
+// in seqs with at least order predecessors. Each element of seqs is one
+// sequence of frames of length numVisible. Returns an error, without
+// training, if a frame has the wrong length or no sequence is longer than
+// order.
+func (self *CRBM) Train(seqs [][][]float64, iters int, verbose bool) error {
+  rbm, tr := self.rbm, self.trainer
+  type frame struct{ s, t int }
+  var frames []frame
+  for s, seq := range seqs {
+    for t, x := range seq {
+      if len(x) != rbm.d {
+        return fmt.Errorf("rbm: frame %d of sequence %d has length %d, want %d", t, s, len(x), rbm.d)
+      }
+    }
+    for t := self.order; t < len(seq); t++ {
+      frames = append(frames, frame{s, t})
+    }
+  }
+  if err := checkTrainingSize(len(frames), iters); err != nil {
+    return err
+  }
+  if err := tr.startSampler(len(frames)); err != nil {
+    return err
+  }
+  for it := 0; it < iters; it++ {
+    if verbose && (it + 1) % tr.logInterval() == 0 {
+      tr.logf("Training iteration: %d\n", it + 1)
+    }
+    var dw [][]float64
+    var da, db []float64
+    dA, dB := zeros(len(self.A), len(self.A[0])), zeros(len(self.B), len(self.B[0]))
+    for k := 0; k < tr.batchSize; k++ {
+      f := frames[tr.drawExample(len(frames))]
+      seq := seqs[f.s]
+      u := self.history(seq[:f.t])
+      dwn, dan, dbn, _ := self.conditioned(u).gradientFrom(seq[f.t], nil, nil)
+      if k == 0 {
+        dw, da, db = dwn, dan, dbn
+      } else {
+        addTo(dw, dwn)
+        addTo([][]float64{da}, [][]float64{dan})
+        addTo([][]float64{db}, [][]float64{dbn})
+      }
+      for i, dai := range dan {
+        for l, ul := range u {
+          dA[i][l] += dai * ul
+        }
+      }
+      for j, dbj := range dbn {
+        for l, ul := range u {
+          dB[j][l] += dbj * ul
+        }
+      }
+    }
+    if tr.weightDecay != 0 {
+      for i := 0; i < rbm.d; i++ {
+        for j := 0; j < rbm.m; j++ {
+          dw[i][j] -= tr.weightDecay * float64(tr.batchSize) * rbm.weight(i, j)
+        }
+      }
+      addScaled(dA, self.A, -tr.weightDecay * float64(tr.batchSize))
+      addScaled(dB, self.B, -tr.weightDecay * float64(tr.batchSize))
+    }
+    eps := tr.learningRate() / float64(tr.batchSize)
+    tr.applyGradient(eps, dw, da, db)
+    tr.steps++
+    self.updateDynamic(eps, dA, dB)
+  }
+  return nil
+}
+
+// A += eps dA and B += eps dB, through velocities if the trainer has
+// momentum.
+func (self *CRBM) updateDynamic(eps float64, dA, dB [][]float64) {
+  mu := self.trainer.momentum
+  if mu == 0 {
+    addScaled(self.A, dA, eps)
+    addScaled(self.B, dB, eps)
+    return
+  }
+  if self.velA == nil {
+    self.velA, self.velB = zeros(len(self.A), len(self.A[0])), zeros(len(self.B), len(self.B[0]))
+  }
+  momentumUpdate(self.A, self.velA, dA, mu, eps)
+  momentumUpdate(self.B, self.velB, dB, mu, eps)
+}
+
+// vel = mu vel + eps g; x += vel
+func momentumUpdate(x, vel, g [][]float64, mu, eps float64) {
+  for k := range x {
+    for l := range x[k] {
+      vel[k][l] = mu * vel[k][l] + eps * g[k][l]
+      x[k][l] += vel[k][l]
+    }
+  }
+}
+
+// Continues the sequence past (at least order frames) by steps frames. Each
+// frame starts from the previous one, runs gibbsIters Gibbs steps under its
+// dynamic biases and is taken as the mean-field E[v | h] of the last hidden
+// sample.
+func (self *CRBM) Generate(past [][]float64, steps, gibbsIters int) [][]float64 {
+  seq := append([][]float64(nil), past...)
+  out := make([][]float64, steps)
+  for t := range out {
+    cond := self.conditioned(self.history(seq))
+    v := seq[len(seq) - 1]
+    h := cond.sampleHidden(v)
+    for k := 1; k < gibbsIters; k++ {
+      h = cond.sampleHidden(cond.sampleVisible(h))
+    }
+    out[t] = cond.visibleMeans(h)
+    seq = append(seq, out[t])
+  }
+  return out
+}
+
+func zeros(rows, cols int) [][]float64 {
+  x := make([][]float64, rows)
+  for k := range x {
+    x[k] = make([]float64, cols)
+  }
+  return x
+}
+
+// x += y
+func addTo(x, y [][]float64) {
+  addScaled(x, y, 1)
+}
+
+// x += s * y
+func addScaled(x, y [][]float64, s float64) {
+  for k := range x {
+    for l := range x[k] {
+      x[k][l] += s * y[k][l]
+    }
+  }
+}
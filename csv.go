@@ -0,0 +1,88 @@
+package rbm
+
+import (
+  "encoding/csv"
+  "fmt"
+  "io"
+  "math/rand"
+  "os"
+  "strconv"
+  "strings"
+)
+
+// Reads a CSV file of real values, one example per row, and binarizes it at
+// threshold (see Binarize). A first row that doesn't parse as numbers is
+// taken as a header and skipped.
+func LoadCSV(path string, threshold float64) ([][]int, error) {
+  x, err := LoadCSVFloat(path)
+  if err != nil {
+    return nil, err
+  }
+  return Binarize(x, threshold), nil
+}
+
+// Same as LoadCSV without the binarization, e.g. for Gaussian visibles.
+func LoadCSVFloat(path string) ([][]float64, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  return readCSV(f)
+}
+
+func readCSV(r io.Reader) ([][]float64, error) {
+  cr := csv.NewReader(r)
+  cr.TrimLeadingSpace = true
+  var x [][]float64
+  for line := 1; ; line++ {
+    record, err := cr.Read()
+    if err == io.EOF {
+      return x, nil
+    }
+    if err != nil {
+      return nil, err
+    }
+    row := make([]float64, len(record))
+    for i, field := range record {
+      if row[i], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+        break
+      }
+    }
+    if err != nil {
+      if line == 1 {
+        continue
+      }
+      return nil, fmt.Errorf("rbm: line %d: %v", line, err)
+    }
+    x = append(x, row)
+  }
+}
+
+// 1 where x >= threshold, 0 elsewhere.
+func Binarize(x [][]float64, threshold float64) [][]int {
+  v := make([][]int, len(x))
+  for n, xn := range x {
+    v[n] = make([]int, len(xn))
+    for i, xi := range xn {
+      if xi >= threshold {
+        v[n][i] = 1
+      }
+    }
+  }
+  return v
+}
+
+// Treats the values of x, which must lie in [0, 1], as probabilities and
+// draws a binary sample of each with r, the usual stochastic binarization of
+// grey-scale images. r must not be nil.
+func SampleBinary(x [][]float64, r *rand.Rand) [][]int {
+  v := make([][]int, len(x))
+  for n, xn := range x {
+    v[n] = make([]int, len(xn))
+    for i, xi := range xn {
+      v[n][i] = bernoulli(r, xi)
+    }
+  }
+  return v
+}
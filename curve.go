@@ -0,0 +1,32 @@
+package rbm
+
+// Learning curve of a training run, returned by the Train and TrainEpochs
+// methods for plotting and for comparing hyperparameters. Train adds an
+// entry every log interval (see WithLogInterval) and after its last
+// iteration, TrainEpochs one after every epoch; entry k of each slice belongs
+// to the same point of the run.
+type History struct {
+  Iteration []int               // gradient steps taken in the run
+  ReconstructionError []float64 // mean over the latest mini-batch
+  PseudoLikelihood []float64    // estimated mean over (up to) the first 100 examples; binary visible units only
+  LearningRate []float64        // the scheduled learning rate at that point
+}
+
+// Number of entries.
+func (self *History) Len() int {
+  return len(self.Iteration)
+}
+
+// Appends the point p of a run of t over the N examples, returning the
+// pseudo-likelihood (0 unless the visible units are binary).
+func (self *History) record(t *Trainer, p Progress, N int, example func(n int) []float64) float64 {
+  self.Iteration = append(self.Iteration, p.Iteration)
+  self.ReconstructionError = append(self.ReconstructionError, p.ReconstructionError)
+  self.LearningRate = append(self.LearningRate, t.learningRate())
+  if t.model.visibleType != Binary {
+    return 0
+  }
+  pl := t.model.monitorPseudoLikelihood(N, example, p.Iteration)
+  self.PseudoLikelihood = append(self.PseudoLikelihood, pl)
+  return pl
+}
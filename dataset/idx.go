@@ -0,0 +1,138 @@
+// Package dataset loads the standard RBM benchmark data, MNIST and
+// Fashion-MNIST, from their IDX files.
+package dataset
+
+import (
+  "bufio"
+  "compress/gzip"
+  "encoding/binary"
+  "fmt"
+  "io"
+  "math"
+  "os"
+  "strings"
+
+  "github.com/aotimme/rbm"
+)
+
+// Reads an IDX file (magic 0x0000, a type byte, the number of dimensions,
+// big-endian uint32 sizes, then the data) and returns its elements as
+// float64 in row-major order along with its dimensions.
+func ReadIDX(r io.Reader) (data []float64, dims []int, err error) {
+  br := bufio.NewReader(r)
+  var magic [4]byte
+  if _, err = io.ReadFull(br, magic[:]); err != nil {
+    return nil, nil, err
+  }
+  if magic[0] != 0 || magic[1] != 0 {
+    return nil, nil, fmt.Errorf("dataset: not an IDX file")
+  }
+  size := 1
+  for k := 0; k < int(magic[3]); k++ {
+    var n uint32
+    if err = binary.Read(br, binary.BigEndian, &n); err != nil {
+      return nil, nil, err
+    }
+    dims = append(dims, int(n))
+    size *= int(n)
+  }
+  width, decode := idxDecoder(magic[2])
+  if decode == nil {
+    return nil, nil, fmt.Errorf("dataset: unsupported IDX type 0x%02x", magic[2])
+  }
+  buf := make([]byte, width)
+  data = make([]float64, size)
+  for k := range data {
+    if _, err = io.ReadFull(br, buf); err != nil {
+      return nil, nil, err
+    }
+    data[k] = decode(buf)
+  }
+  return data, dims, nil
+}
+
+func idxDecoder(kind byte) (int, func([]byte) float64) {
+  be := binary.BigEndian
+  switch kind {
+  case 0x08:
+    return 1, func(b []byte) float64 { return float64(b[0]) }
+  case 0x09:
+    return 1, func(b []byte) float64 { return float64(int8(b[0])) }
+  case 0x0B:
+    return 2, func(b []byte) float64 { return float64(int16(be.Uint16(b))) }
+  case 0x0C:
+    return 4, func(b []byte) float64 { return float64(int32(be.Uint32(b))) }
+  case 0x0D:
+    return 4, func(b []byte) float64 { return float64(math.Float32frombits(be.Uint32(b))) }
+  case 0x0E:
+    return 8, func(b []byte) float64 { return math.Float64frombits(be.Uint64(b)) }
+  }
+  return 0, nil
+}
+
+// Opens path, gunzipping it if its name ends in .gz as the files are
+// distributed.
+func loadIDX(path string) ([]float64, []int, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, nil, err
+  }
+  defer f.Close()
+  var r io.Reader = f
+  if strings.HasSuffix(path, ".gz") {
+    gz, err := gzip.NewReader(f)
+    if err != nil {
+      return nil, nil, err
+    }
+    defer gz.Close()
+    r = gz
+  }
+  return ReadIDX(r)
+}
+
+// Reads an IDX image file (e.g. train-images-idx3-ubyte) as one flattened
+// image per row, with pixels scaled from 0..255 to [0, 1].
+func LoadImages(path string) ([][]float64, error) {
+  data, dims, err := loadIDX(path)
+  if err != nil {
+    return nil, err
+  }
+  if len(dims) < 2 {
+    return nil, fmt.Errorf("dataset: %s holds %d-dimensional data, not images", path, len(dims))
+  }
+  N := dims[0]
+  size := len(data) / N
+  images := make([][]float64, N)
+  for n := range images {
+    images[n] = data[n * size:(n + 1) * size]
+    for i := range images[n] {
+      images[n][i] /= 255
+    }
+  }
+  return images, nil
+}
+
+// Same as LoadImages with pixels binarized at threshold (0.5 is usual).
+func LoadBinarizedImages(path string, threshold float64) ([][]int, error) {
+  images, err := LoadImages(path)
+  if err != nil {
+    return nil, err
+  }
+  return rbm.Binarize(images, threshold), nil
+}
+
+// Reads an IDX label file (e.g. train-labels-idx1-ubyte).
+func LoadLabels(path string) ([]int, error) {
+  data, dims, err := loadIDX(path)
+  if err != nil {
+    return nil, err
+  }
+  if len(dims) != 1 {
+    return nil, fmt.Errorf("dataset: %s holds %d-dimensional data, not labels", path, len(dims))
+  }
+  labels := make([]int, len(data))
+  for n, x := range data {
+    labels[n] = int(x)
+  }
+  return labels, nil
+}
@@ -0,0 +1,120 @@
+package rbm
+
+import (
+  "fmt"
+  "math/rand"
+)
+
+// Deep Belief Network: a stack of RBMs where the hidden layer of each is the
+// visible layer of the next, trained greedily one layer at a time.
+type DBN struct {
+  layers []*RBM
+  trainers []*Trainer // of the layers
+}
+
+// sizes lists the unit counts bottom-up, starting with the visible layer, so
+// a 784-500-500-2000 network has sizes {784, 500, 500, 2000}. cdt, r and opts
+// are passed to every layer; the training options are set with
+// SetTrainOptions.
+func NewDBN(sizes []int, cdt int, r *rand.Rand, opts ...Option) (self *DBN) {
+  if len(sizes) < 2 {
+    panic("rbm: a DBN needs at least two layer sizes")
+  }
+  self = new(DBN)
+  for k := 0; k + 1 < len(sizes); k++ {
+    layer := NewRBM(sizes[k], sizes[k + 1], cdt, r, opts...)
+    self.layers = append(self.layers, layer)
+    self.trainers = append(self.trainers, NewTrainer(layer))
+  }
+  return
+}
+
+// The RBMs of the stack, bottom first.
+func (self *DBN) Layers() []*RBM {
+  return append([]*RBM(nil), self.layers...)
+}
+
+// The trainers of the layers, bottom first, which Train uses.
+func (self *DBN) Trainers() []*Trainer {
+  return append([]*Trainer(nil), self.trainers...)
+}
+
+// Applies opts to the trainer of every layer. Options with state of their
+// own, such as WithOptimizer or WithNegativePhase, should go to each trainer
+// separately (see Trainers).
+func (self *DBN) SetTrainOptions(opts ...TrainOption) {
+  for _, t := range self.trainers {
+    t.SetOptions(opts...)
+  }
+}
+
+// Greedy layer-wise training: each layer is trained for iters iterations on
+// the hidden expectations of the layer below it. Returns an error, without
+// training, if the data don't match the visible layer.
+func (self *DBN) Train(v [][]int, iters int, verbose bool) error {
+  if err := self.layers[0].checkInts(v); err != nil {
+    return err
+  }
+  data := make([][]float64, len(v))
+  for n := range v {
+    data[n] = toFloats(v[n])
+  }
+  for k, layer := range self.layers {
+    t := self.trainers[k]
+    if verbose {
+      t.logf("Training layer %d of %d\n", k + 1, len(self.layers))
+    }
+    if _, err := t.TrainFloat(data, iters, verbose); err != nil {
+      return err
+    }
+    if k + 1 < len(self.layers) {
+      data = layer.hiddenMeansBatch(data)
+    }
+  }
+  return nil
+}
+
+// Top layer hidden expectations of each example, propagating mean-field
+// activations up through the stack.
+func (self *DBN) Transform(vs [][]int) [][]float64 {
+  out := self.layers[0].Transform(vs)
+  for _, layer := range self.layers[1:] {
+    out = layer.TransformFloat(out)
+  }
+  return out
+}
+
+// Runs iters Gibbs steps in the top-level RBM, which models the prior over
+// the penultimate layer, then samples down through the directed lower layers
+// to a visible vector.
+func (self *DBN) Generate(iters int) []int {
+  top := self.layers[len(self.layers) - 1]
+  v := top.generate(iters, nil)
+  for k := len(self.layers) - 2; k >= 0; k-- {
+    v = self.layers[k].sampleVisible(v)
+  }
+  return toInts(v)
+}
+
+// Unrolls the stack into a feed-forward classifier: each layer computes the
+// hidden expectations of its RBM, from copies of its weights and hidden
+// biases, and a softmax output layer over numClasses labels, initialized to
+// small random weights, sits on top. Fine-tune it with FineTune; the DBN
+// itself is left unchanged.
+func (self *DBN) Classifier(numClasses int) *Network {
+  if numClasses < 2 {
+    panic(fmt.Sprintf("rbm: a classifier needs at least two classes, got %d", numClasses))
+  }
+  var layers []*netLayer
+  for _, layer := range self.layers {
+    layers = append(layers, rbmLayer(layer))
+  }
+  top := self.layers[len(self.layers) - 1]
+  out := newNetLayer(top.m, numClasses, Softmax)
+  for i := range out.w {
+    for j := range out.w[i] {
+      out.w[i][j] = 0.01 * normal(top.r)
+    }
+  }
+  return newNetwork(append(layers, out), top.r)
+}
@@ -0,0 +1,114 @@
+package rbm
+
+import (
+  "context"
+  "fmt"
+  "math/rand"
+)
+
+// Where a Worker fetches the current parameters from and pushes its
+// gradients to. *ParameterServer (same process) and *ParameterClient
+// (net/rpc) implement it; other transports, e.g. gRPC, only need these two
+// calls. The fetched models have no random source of their own.
+type ParameterStore interface {
+  GetParams() (*RBM, error)
+  PushGradient(dW [][]float64, dA, dB []float64) error
+}
+
+// One data-parallel training worker: it repeatedly fetches the parameters
+// from the store, computes the CD gradient of a random mini-batch of its
+// shard and pushes it back. Run one per process (or goroutine) with a
+// ParameterServer coordinating them; whether updates are synchronous or
+// asynchronous is up to the server.
+type Worker struct {
+  store ParameterStore
+  shard [][]float64
+  batchSize int
+  r *rand.Rand
+  opts []Option
+  checked bool // shard validated against the model
+}
+
+// A worker training on shard, each example one value per visible unit.
+// opts are applied to every fetched model before its gradient is computed,
+// e.g. WithCDK; the unit types come with the parameters. r draws the
+// mini-batches and the model's samples. Panics unless batchSize is positive
+// and r is non-nil.
+func NewWorker(store ParameterStore, shard [][]int, batchSize int, r *rand.Rand, opts ...Option) *Worker {
+  data := make([][]float64, len(shard))
+  for n, v := range shard {
+    data[n] = toFloats(v)
+  }
+  return NewWorkerFloat(store, data, batchSize, r, opts...)
+}
+// Same as NewWorker for real-valued data.
+func NewWorkerFloat(store ParameterStore, shard [][]float64, batchSize int, r *rand.Rand, opts ...Option) *Worker {
+  if batchSize < 1 {
+    panic(fmt.Sprintf("rbm: worker batch size must be positive, got %d", batchSize))
+  }
+  if r == nil {
+    panic("rbm: nil *rand.Rand, each worker needs its own source")
+  }
+  return &Worker{store: store, shard: shard, batchSize: batchSize, r: r, opts: opts}
+}
+
+// Runs steps fetch-compute-push rounds, or until ctx is done. Returns the
+// first error of the store, or one for an empty shard or a shard that
+// doesn't match the model.
+func (self *Worker) Run(ctx context.Context, steps int) error {
+  for step := 0; step < steps; step++ {
+    if err := ctx.Err(); err != nil {
+      return err
+    }
+    if err := self.Step(); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// One fetch-compute-push round.
+func (self *Worker) Step() error {
+  if err := checkTrainingSize(len(self.shard), 1); err != nil {
+    return err
+  }
+  model, err := self.store.GetParams()
+  if err != nil {
+    return err
+  }
+  model.SetOptions(append([]Option{WithRand(self.r)}, self.opts...)...)
+  if !self.checked {
+    if err := model.checkFloats(self.shard); err != nil {
+      return err
+    }
+    self.checked = true
+  }
+  batch := make([][]float64, self.batchSize)
+  for k := range batch {
+    batch[k] = self.shard[int(uniform(self.r) * float64(len(self.shard)))]
+  }
+  dw, da, db := model.batchGradient(batch)
+  return self.store.PushGradient(dw, da, db)
+}
+
+// The CD gradient averaged over batch, dw d x m.
+func (self *RBM) batchGradient(batch [][]float64) (dw [][]float64, da, db []float64) {
+  dw, da, db = zeros(self.d, self.m), make([]float64, self.d), make([]float64, self.m)
+  for _, v := range batch {
+    dwn, dan, dbn, _ := self.gradientFrom(v, nil, nil)
+    addTo(dw, dwn)
+    addTo([][]float64{da}, [][]float64{dan})
+    addTo([][]float64{db}, [][]float64{dbn})
+  }
+  s := 1 / float64(len(batch))
+  for i := range dw {
+    for j := range dw[i] {
+      dw[i][j] *= s
+    }
+    da[i] *= s
+  }
+  for j := range db {
+    db[j] *= s
+  }
+  return
+}
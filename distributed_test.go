@@ -0,0 +1,44 @@
+package rbm
+
+import (
+  "context"
+  "math/rand"
+  "sync"
+  "testing"
+)
+
+// In synchronous mode every update takes one gradient from each worker, and
+// the workers' shards are learned.
+func TestSynchronousRounds(t *testing.T) {
+  data := [][]int{{1, 1, 1, 0, 0, 0}, {0, 0, 0, 1, 1, 1}}
+  tr := NewTrainer(New(6, 2, WithSeed(1)), WithLearningRate(0.5))
+  ps, err := NewParameterServer("127.0.0.1:0", tr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer ps.Close()
+  before := tr.Model().ReconstructionErrorBatch(data)
+  ps.SetSynchronous(3)
+  var wg sync.WaitGroup
+  errs := make([]error, 3)
+  for k := range errs {
+    wg.Add(1)
+    go func(k int) {
+      defer wg.Done()
+      w := NewWorker(ps, data, 2, rand.New(NewSource(int64(k))))
+      errs[k] = w.Run(context.Background(), 50)
+    }(k)
+  }
+  wg.Wait()
+  for k, err := range errs {
+    if err != nil {
+      t.Fatalf("worker %d: %v", k, err)
+    }
+  }
+  if ps.Updates() != 50 {
+    t.Errorf("%d updates for 50 rounds of 3 workers", ps.Updates())
+  }
+  if after := tr.Model().ReconstructionErrorBatch(data); after >= before {
+    t.Errorf("reconstruction error %g, %g before training", after, before)
+  }
+}
@@ -0,0 +1,15 @@
+// Package rbm implements Restricted Boltzmann Machines trained by
+// contrastive divergence, with Gibbs sampling for generation.
+//
+// Concurrency
+//
+// Methods that only read the parameters are safe to call from many
+// goroutines at once: FreeEnergy, PseudoLikelihood, Reconstruct,
+// ReconstructionError, Transform, HiddenLayerExpectation, the *Batch and
+// *Float variants of these, and serialization. Sampling methods
+// (SampleHiddenLayer, GenerateVisible, SampleClamped, Inpaint, ...) also
+// draw from the model's random source; a *rand.Rand isn't safe for
+// concurrent use, so give every goroutine its own Fork. Training (see
+// Trainer) and the setters modify the model and must not run concurrently
+// with anything else.
+package rbm
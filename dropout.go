@@ -0,0 +1,58 @@
+package rbm
+
+import (
+  "math/rand"
+)
+
+// Dropout (Srivastava et al., 2014): each training example drops every
+// hidden unit with probability rate for the whole of its CD chain, and the
+// surviving units see their inputs scaled by 1 / (1 - rate). The stored
+// weights are thus the weights of the full network already rescaled for
+// inference, so sampling and inference need no dropout-specific handling.
+func WithDropout(rate float64) TrainOption {
+  return func(self *Trainer) {
+    if rate < 0 || rate >= 1 {
+      rate = 0
+    }
+    self.dropout = rate
+  }
+}
+
+// Samples which hidden units an example keeps, drawing from r.
+func (self *Trainer) sampleDropoutMask(r *rand.Rand) []bool {
+  keep := make([]bool, self.model.m)
+  for j := range keep {
+    keep[j] = uniform(r) >= self.dropout
+  }
+  return keep
+}
+
+// Rescales the weighted part of the layer inputs x = bias + W.. of a
+// dropout training copy.
+func (self *RBM) dropoutInputs(x, bias []float64) {
+  s := self.dropScale
+  for k := range x {
+    x[k] = bias[k] + s * (x[k] - bias[k])
+  }
+}
+
+// Gradient of the stored weights from that of the rescaled ones, with the
+// units dropped by mask cleared (a persistent negative phase still moves
+// them otherwise).
+func (self *Trainer) dropoutGradient(mask []bool, dw [][]float64, db []float64) {
+  keep := 1 - self.dropout
+  for j, on := range mask {
+    if !on {
+      db[j] = 0
+    }
+  }
+  for i := range dw {
+    for j, on := range mask {
+      if on {
+        dw[i][j] *= keep
+      } else {
+        dw[i][j] = 0
+      }
+    }
+  }
+}
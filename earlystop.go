@@ -0,0 +1,97 @@
+package rbm
+
+import (
+  "math"
+)
+
+// What early stopping monitors on the validation set.
+type ValidationMetric int
+
+const (
+  ValidateReconstruction ValidationMetric = iota // mean reconstruction error
+  ValidatePseudoLikelihood                       // mean log pseudo-likelihood (binary visibles only)
+)
+
+type earlyStopping struct {
+  data [][]float64
+  metric ValidationMetric
+  every int    // iterations between checks
+  patience int // checks without improvement before stopping
+  // state of the current training run
+  best float64
+  bestParams *Params
+  bad int
+}
+
+// Early stopping: every `every` iterations (epochs with TrainEpochs) the
+// model is scored on the validation set, and training stops once patience
+// consecutive checks fail to improve on the best score. The model is then
+// restored to the parameters of its best check.
+func WithEarlyStopping(validation [][]int, metric ValidationMetric, every, patience int) TrainOption {
+  data := make([][]float64, len(validation))
+  for n, v := range validation {
+    data[n] = toFloats(v)
+  }
+  return WithEarlyStoppingFloat(data, metric, every, patience)
+}
+
+// Same as WithEarlyStopping for real-valued validation data.
+func WithEarlyStoppingFloat(validation [][]float64, metric ValidationMetric, every, patience int) TrainOption {
+  return func(self *Trainer) {
+    if len(validation) == 0 {
+      self.earlyStop = nil
+      return
+    }
+    if every < 1 {
+      every = 1
+    }
+    if patience < 1 {
+      patience = 1
+    }
+    self.earlyStop = &earlyStopping{data: validation, metric: metric, every: every, patience: patience}
+  }
+}
+
+// Turns off early stopping.
+func WithoutEarlyStopping() TrainOption {
+  return func(self *Trainer) {
+    self.earlyStop = nil
+  }
+}
+
+// Validation loss of the current parameters; lower is better.
+func (self *RBM) validationLoss(data [][]float64, metric ValidationMetric) float64 {
+  loss := 0.0
+  for _, v := range data {
+    if metric == ValidatePseudoLikelihood {
+      loss -= self.logPseudoLikelihood(v)
+    } else {
+      loss += self.ReconstructionErrorFloat(v)
+    }
+  }
+  return loss / float64(len(data))
+}
+
+func (es *earlyStopping) begin() {
+  es.best, es.bestParams, es.bad = math.Inf(1), nil, 0
+}
+
+// Scores the model, snapshotting it on improvement; false once patience has
+// run out.
+func (es *earlyStopping) check(self *RBM) bool {
+  loss := self.validationLoss(es.data, es.metric)
+  if loss < es.best {
+    es.best, es.bestParams, es.bad = loss, self.params(), 0
+    return true
+  }
+  es.bad++
+  return es.bad < es.patience
+}
+
+// Restores the best snapshot of the run, if it isn't the current model.
+func (es *earlyStopping) end(self *RBM) {
+  if es.bestParams != nil && es.bad > 0 {
+    self.load(es.bestParams)
+  }
+  es.bestParams = nil
+}
@@ -0,0 +1,40 @@
+package rbm
+
+import (
+  "testing"
+)
+
+// Validation data the training data push away from: training stops after
+// patience bad checks and restores the best one.
+func TestEarlyStoppingRestoresBest(t *testing.T) {
+  data := [][]int{{1, 1, 1, 0, 0, 0}, {1, 1, 0, 0, 0, 0}}
+  validation := [][]int{{0, 0, 0, 1, 1, 1}, {0, 0, 1, 1, 1, 1}}
+  loss := func(m *RBM) float64 {
+    return m.validationLoss([][]float64{toFloats(validation[0]), toFloats(validation[1])}, ValidatePseudoLikelihood)
+  }
+  var losses []float64
+  tr := NewTrainer(New(6, 3, WithSeed(2)), WithLearningRate(0.5),
+    WithEarlyStopping(validation, ValidatePseudoLikelihood, 2, 3),
+    WithCallback(2, func(s TrainingStatus) bool {
+      losses = append(losses, loss(s.Model))
+      return true
+    }))
+  if _, err := tr.Train(data, 200, false); err != nil {
+    t.Fatal(err)
+  }
+  if len(losses) >= 100 {
+    t.Fatalf("%d checks: never stopped", len(losses))
+  }
+  best := 0
+  for k := range losses {
+    if losses[k] < losses[best] {
+      best = k
+    }
+  }
+  if len(losses) - 1 - best != 3 {
+    t.Errorf("stopped %d checks after the best, patience 3", len(losses) - 1 - best)
+  }
+  if got := loss(tr.Model()); got != losses[best] {
+    t.Errorf("restored loss %g, best %g", got, losses[best])
+  }
+}
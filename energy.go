@@ -0,0 +1,88 @@
+package rbm
+
+import (
+  "math"
+)
+
+// log(1 + exp(x)) without overflow
+func softplus(x float64) float64 {
+  if x > 0 {
+    return x + math.Log1p(math.Exp(-x))
+  }
+  return math.Log1p(math.Exp(x))
+}
+
+// joint energy E(v, h) = -a.v - b.h - v'Wh, with -a.v replaced by
+// |v - a|^2 / 2 for Gaussian visible units and -a.v - log h(v) for count
+// units (see logBaseMeasure)
+func (self *RBM) energy(v, h []float64) float64 {
+  e := 0.0
+  for i := 0; i < self.d; i++ {
+    if self.visibleType == Gaussian {
+      diff := v[i] - self.a[i]
+      e += diff * diff / 2
+    } else if isCount(self.visibleType) {
+      e -= self.logBaseMeasure(v[i])
+    }
+    if v[i] == 0 {
+      continue
+    }
+    x := 0.0
+    if self.visibleType != Gaussian {
+      x = self.a[i]
+    }
+    for j := 0; j < self.m; j++ {
+      x += self.weight(i, j) * h[j]
+    }
+    e -= x * v[i]
+  }
+  for j := 0; j < self.m; j++ {
+    e -= self.b[j] * h[j]
+  }
+  return e
+}
+
+// F(v) = -log sum_h exp(-E(v, h)) = -a.v - sum_j log(1 + exp(b_j + w_j.v)),
+// again with |v - a|^2 / 2 for Gaussian visibles and the base measure of
+// count units. Hidden units are summed out as binary units, which for NReLU
+// hiddens is only an approximation.
+func (self *RBM) freeEnergy(v []float64) float64 {
+  f := 0.0
+  for i := 0; i < self.d; i++ {
+    if self.visibleType == Gaussian {
+      diff := v[i] - self.a[i]
+      f += diff * diff / 2
+    } else {
+      f -= self.a[i] * v[i]
+    }
+    if isCount(self.visibleType) {
+      f -= self.logBaseMeasure(v[i])
+    }
+  }
+  for _, x := range self.hiddenInputs(v) {
+    f -= softplus(x)
+  }
+  return f
+}
+
+// Free energy of v: lower means the model finds v more probable, since
+// p(v) = exp(-F(v)) / Z.
+func (self *RBM) FreeEnergy(v []int) float64 {
+  return self.freeEnergy(toFloats(v))
+}
+func (self *RBM) FreeEnergyFloat(v []float64) float64 {
+  return self.freeEnergy(v)
+}
+
+// Joint energy E(v, h) = -a.v - b.h - v'Wh of a visible and hidden
+// configuration, with the Gaussian and count unit variants of the visible
+// term; p(v, h) = exp(-E(v, h)) / Z.
+func (self *RBM) Energy(v, h []int) float64 {
+  return self.EnergyFloat(toFloats(v), toFloats(h))
+}
+// Same as Energy for real-valued visible or hidden (e.g. NReLU) states.
+func (self *RBM) EnergyFloat(v, h []float64) float64 {
+  checkLength("visible", len(v), self.d)
+  checkLength("hidden", len(h), self.m)
+  return self.energy(v, h)
+}
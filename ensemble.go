@@ -0,0 +1,161 @@
+package rbm
+
+import (
+  "math"
+  "sync"
+)
+
+// Independent restarts of one RBM configuration. CD training is noisy enough
+// that models differing only in their seed can end up far apart, so it pays
+// to train a few and keep the best, or to use them all as features.
+type Ensemble struct {
+  members []*RBM
+  trainers []*Trainer // of the members
+  scores []float64 // validation losses from the last Select, lower is better
+  best int
+}
+
+// k members, member n trained by the trainer build(n) returns, which should
+// seed its model with n (e.g. WithSeed) and have its own copies of stateful
+// options such as an Optimizer or early stopping. All members must have the
+// same shape.
+func NewEnsemble(k int, build func(seed int64) *Trainer) *Ensemble {
+  if k < 1 {
+    panic("rbm: an ensemble needs at least one member")
+  }
+  self := &Ensemble{members: make([]*RBM, k), trainers: make([]*Trainer, k)}
+  for n := range self.members {
+    self.trainers[n] = build(int64(n))
+    self.members[n] = self.trainers[n].model
+    if m := self.members[n]; m.d != self.members[0].d || m.m != self.members[0].m {
+      panic("rbm: ensemble members differ in shape")
+    }
+  }
+  return self
+}
+
+func (self *Ensemble) Members() []*RBM {
+  return append([]*RBM(nil), self.members...)
+}
+
+// The trainers of the members, in the same order.
+func (self *Ensemble) Trainers() []*Trainer {
+  return append([]*Trainer(nil), self.trainers...)
+}
+
+// Trains every member with Train, one goroutine per member if parallel
+// (the members' callbacks then run concurrently). Returns the first error.
+func (self *Ensemble) Train(v [][]int, iters int, parallel bool) error {
+  return self.each(parallel, func(t *Trainer) error {
+    _, err := t.Train(v, iters, false)
+    return err
+  })
+}
+// Same as Train for real-valued visible data (Gaussian units).
+func (self *Ensemble) TrainFloat(v [][]float64, iters int, parallel bool) error {
+  return self.each(parallel, func(t *Trainer) error {
+    _, err := t.TrainFloat(v, iters, false)
+    return err
+  })
+}
+
+// Same as Train with TrainEpochs.
+func (self *Ensemble) TrainEpochs(v [][]int, epochs int, parallel bool) error {
+  return self.each(parallel, func(t *Trainer) error {
+    _, err := t.TrainEpochs(v, epochs, false)
+    return err
+  })
+}
+
+func (self *Ensemble) each(parallel bool, fn func(t *Trainer) error) error {
+  errs := make([]error, len(self.trainers))
+  if parallel {
+    var wg sync.WaitGroup
+    for n, t := range self.trainers {
+      wg.Add(1)
+      go func(n int, t *Trainer) {
+        defer wg.Done()
+        errs[n] = fn(t)
+      }(n, t)
+    }
+    wg.Wait()
+  } else {
+    for n, t := range self.trainers {
+      errs[n] = fn(t)
+    }
+  }
+  for _, err := range errs {
+    if err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Scores every member on the validation set with metric and returns the best
+// one, which Best returns from then on.
+func (self *Ensemble) Select(validation [][]int, metric ValidationMetric) *RBM {
+  data := make([][]float64, len(validation))
+  for n, v := range validation {
+    data[n] = toFloats(v)
+  }
+  return self.SelectFloat(data, metric)
+}
+// Same as Select for real-valued validation data.
+func (self *Ensemble) SelectFloat(validation [][]float64, metric ValidationMetric) *RBM {
+  self.scores = make([]float64, len(self.members))
+  best := math.Inf(1)
+  for n, m := range self.members {
+    self.scores[n] = m.validationLoss(validation, metric)
+    if self.scores[n] < best {
+      best, self.best = self.scores[n], n
+    }
+  }
+  return self.members[self.best]
+}
+
+// The member chosen by the last Select, the first one before that.
+func (self *Ensemble) Best() *RBM {
+  return self.members[self.best]
+}
+
+// Validation losses of the members from the last Select (lower is better),
+// nil before.
+func (self *Ensemble) Scores() []float64 {
+  return append([]float64(nil), self.scores...)
+}
+
+// Hidden expectations of every member for each example, concatenated into
+// one k*m feature vector.
+func (self *Ensemble) Transform(vs [][]int) [][]float64 {
+  out := make([][]float64, len(vs))
+  for _, m := range self.members {
+    for n, h := range m.Transform(vs) {
+      out[n] = append(out[n], h...)
+    }
+  }
+  return out
+}
+
+// Hidden expectations averaged over the members. Hidden units are only
+// interchangeable across members that started from the same weights (the
+// same seed for WithWeightInit, say, with training noise from different
+// sources); otherwise unit j means something different in each member and
+// Transform is the better choice.
+func (self *Ensemble) TransformMean(vs [][]int) [][]float64 {
+  out := self.members[0].Transform(vs)
+  for _, m := range self.members[1:] {
+    for n, h := range m.Transform(vs) {
+      for j, hj := range h {
+        out[n][j] += hj
+      }
+    }
+  }
+  k := float64(len(self.members))
+  for _, h := range out {
+    for j := range h {
+      h[j] /= k
+    }
+  }
+  return out
+}
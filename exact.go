@@ -0,0 +1,111 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+)
+
+// Largest number of hidden units ExactLogPartition accepts: 2^30 hidden
+// configurations already take minutes.
+const maxExactHidden = 30
+
+// log Z computed exactly by summing over all 2^m hidden configurations, the
+// visible units summed out in closed form for every unit type: softplus for
+// binary units, log-sum-exp for softmax groups, a Gaussian integral for
+// Gaussian units, exp(x) for Poisson and n softplus(x) for Binomial ones.
+// The cost is O(2^m d), fine up to ~25 hidden units; use it to check
+// EstimateLogPartition or to evaluate small models exactly. Panics for NReLU
+// hidden units and for more than 30 of them.
+func (self *RBM) ExactLogPartition() float64 {
+  if self.hiddenType != Binary {
+    panic("rbm: exact partition function needs binary hidden units")
+  }
+  if self.m > maxExactHidden {
+    panic(fmt.Sprintf("rbm: exact partition function over %d hidden units, at most %d supported", self.m, maxExactHidden))
+  }
+  // visit the configurations in Gray code order, so each one differs from
+  // the last in a single unit and x = a + W h and b.h update in O(d)
+  h := make([]float64, self.m)
+  x := append([]float64(nil), self.a...)
+  bh := 0.0
+  max, sum := math.Inf(-1), 0.0
+  for n := uint64(0); n < uint64(1) << uint(self.m); n++ {
+    if n > 0 {
+      j := 0
+      for (n >> uint(j)) & 1 == 0 {
+        j++
+      }
+      sign := 1.0
+      if h[j] == 1 {
+        sign = -1
+      }
+      h[j] += sign
+      bh += sign * self.b[j]
+      for i := 0; i < self.d; i++ {
+        x[i] += sign * self.weight(i, j)
+      }
+    }
+    lp := bh + self.logVisibleSum(x)
+    // streaming log-sum-exp
+    if lp > max {
+      sum = sum * math.Exp(max - lp) + 1
+      max = lp
+    } else {
+      sum += math.Exp(lp - max)
+    }
+  }
+  return max + math.Log(sum)
+}
+
+// log of the sum (or integral) over v of exp(x.v + log h(v)), the visible
+// half of the partition function given the visible inputs x = a + W h.
+func (self *RBM) logVisibleSum(x []float64) float64 {
+  s := 0.0
+  for i, xi := range x {
+    if self.inSoftmax(i) {
+      continue
+    }
+    switch self.visibleType {
+    case Gaussian:
+      // int exp(-(v - a)^2 / 2 + v c) dv with c = x - a
+      c := xi - self.a[i]
+      s += 0.5 * math.Log(2 * math.Pi) + self.a[i] * c + c * c / 2
+    case Poisson:
+      s += math.Exp(xi)
+    case Binomial:
+      s += float64(self.binomialTrials()) * softplus(xi)
+    default:
+      s += softplus(xi)
+    }
+  }
+  for _, group := range self.softmaxGroups {
+    max := math.Inf(-1)
+    for _, i := range group {
+      max = math.Max(max, x[i])
+    }
+    sum := 0.0
+    for _, i := range group {
+      sum += math.Exp(x[i] - max)
+    }
+    s += max + math.Log(sum)
+  }
+  return s
+}
+
+// Mean log-likelihood of data under the exactly computed partition function,
+// see ExactLogPartition for the cost.
+func (self *RBM) ExactLogLikelihood(data [][]int) float64 {
+  return self.LogLikelihood(data, self.ExactLogPartition())
+}
+// Same as ExactLogLikelihood for real-valued data (Gaussian visibles).
+func (self *RBM) ExactLogLikelihoodFloat(data [][]float64) float64 {
+  if len(data) == 0 {
+    return 0
+  }
+  logZ := self.ExactLogPartition()
+  ll := 0.0
+  for _, v := range data {
+    ll -= self.FreeEnergyFloat(v)
+  }
+  return ll / float64(len(data)) - logZ
+}
@@ -0,0 +1,59 @@
+package rbm
+
+import (
+  "math"
+  "math/rand"
+  "testing"
+)
+
+// A small model with random weights, worth checking partition functions on.
+func testModel(d, m int, seed int64) *RBM {
+  self := New(d, m, WithSeed(seed))
+  r := rand.New(NewSource(seed))
+  for i := 0; i < d; i++ {
+    self.a[i] = 0.5 * r.NormFloat64()
+    for j := 0; j < m; j++ {
+      self.setWeight(i, j, r.NormFloat64())
+    }
+  }
+  for j := range self.b {
+    self.b[j] = 0.5 * r.NormFloat64()
+  }
+  return self
+}
+
+// log Z by brute force over the visible configurations.
+func bruteLogPartition(self *RBM) float64 {
+  max, terms := math.Inf(-1), []float64{}
+  for n := 0; n < 1 << uint(self.d); n++ {
+    v := make([]int, self.d)
+    for i := range v {
+      v[i] = (n >> uint(i)) & 1
+    }
+    x := -self.FreeEnergy(v)
+    terms = append(terms, x)
+    max = math.Max(max, x)
+  }
+  sum := 0.0
+  for _, x := range terms {
+    sum += math.Exp(x - max)
+  }
+  return max + math.Log(sum)
+}
+
+func TestExactLogPartition(t *testing.T) {
+  m := testModel(8, 5, 1)
+  exact, brute := m.ExactLogPartition(), bruteLogPartition(m)
+  if math.Abs(exact - brute) > 1e-9 {
+    t.Fatalf("exact log Z %v, brute force %v", exact, brute)
+  }
+}
+
+func TestEstimateLogPartition(t *testing.T) {
+  m := testModel(8, 5, 2)
+  exact := m.ExactLogPartition()
+  estimate := m.EstimateLogPartition(100, 1000, nil)
+  if math.Abs(estimate - exact) > 0.1 {
+    t.Fatalf("AIS log Z %v, exact %v", estimate, exact)
+  }
+}
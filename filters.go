@@ -0,0 +1,66 @@
+package rbm
+
+import (
+  "fmt"
+  "image"
+  "image/color"
+  "image/png"
+  "io"
+  "math"
+  "os"
+)
+
+// Renders the weights of every hidden unit as a width x height grey-scale
+// tile (row-major, like the flattened images it was trained on), the tiles
+// laid out in a near-square grid with a one pixel gap. Each tile is scaled
+// to its own range, black at its most negative weight and white at its most
+// positive, so the filters stay visible whatever their magnitude. On image
+// data a trained model shows strokes, blobs or edges; noise means it hasn't
+// learned much yet.
+func (self *RBM) FilterImage(width, height int) (*image.Gray, error) {
+  if width * height != self.d {
+    return nil, fmt.Errorf("rbm: %d x %d filters do not match %d visible units", width, height, self.d)
+  }
+  cols := int(math.Ceil(math.Sqrt(float64(self.m))))
+  rows := (self.m + cols - 1) / cols
+  img := image.NewGray(image.Rect(0, 0, cols * (width + 1) + 1, rows * (height + 1) + 1))
+  for j := 0; j < self.m; j++ {
+    lo, hi := math.Inf(1), math.Inf(-1)
+    for i := 0; i < self.d; i++ {
+      lo = math.Min(lo, self.weight(i, j))
+      hi = math.Max(hi, self.weight(i, j))
+    }
+    x0 := (j % cols) * (width + 1) + 1
+    y0 := (j / cols) * (height + 1) + 1
+    for i := 0; i < self.d; i++ {
+      g := 0.5
+      if hi > lo {
+        g = (self.weight(i, j) - lo) / (hi - lo)
+      }
+      img.SetGray(x0 + i % width, y0 + i / width, color.Gray{uint8(math.Round(255 * g))})
+    }
+  }
+  return img, nil
+}
+
+// Writes FilterImage(width, height) to w as a PNG.
+func (self *RBM) WriteFilters(w io.Writer, width, height int) error {
+  img, err := self.FilterImage(width, height)
+  if err != nil {
+    return err
+  }
+  return png.Encode(w, img)
+}
+
+// Writes FilterImage(width, height) to a PNG file at path.
+func (self *RBM) SaveFilters(path string, width, height int) error {
+  f, err := os.Create(path)
+  if err != nil {
+    return err
+  }
+  if err := self.WriteFilters(f, width, height); err != nil {
+    f.Close()
+    return err
+  }
+  return f.Close()
+}
@@ -0,0 +1,74 @@
+package rbm
+
+// Stores the weights as float32, halving their memory and cache footprint
+// (a 784 x 4096 model drops from 25MB to 13MB of weights). Activations,
+// gradients and biases stay float64, so only the stored weights lose
+// precision. Existing weights are converted.
+func WithFloat32() Option {
+  return func(self *RBM) {
+    if self.w32 != nil {
+      return
+    }
+    self.w32 = make([]float32, len(self.w))
+    for k, x := range self.w {
+      self.w32[k] = float32(x)
+    }
+    self.w = nil
+  }
+}
+
+// row k of the float32 weight storage
+func (self *RBM) row32(k int) []float32 {
+  n := self.stride()
+  return self.w32[k * n:(k + 1) * n]
+}
+
+// x + row k of the weight storage dotted with v
+func (self *RBM) dotRow(k int, v []float64, x float64) float64 {
+  if self.w32 != nil {
+    for l, wl := range self.row32(k) {
+      x += float64(wl) * v[l]
+    }
+    return x
+  }
+  for l, wl := range self.row(k) {
+    x += wl * v[l]
+  }
+  return x
+}
+
+// x += s * row k of the weight storage
+func (self *RBM) addRow(x []float64, k int, s float64) {
+  if self.w32 != nil {
+    for l, wl := range self.row32(k) {
+      x[l] += float64(wl) * s
+    }
+    return
+  }
+  for l, wl := range self.row(k) {
+    x[l] += wl * s
+  }
+}
+
+// row k of the weight storage += eps * (column k of dw if colMajor, else row k)
+func (self *RBM) updateRow(k int, eps float64, dw [][]float64) {
+  if self.w32 != nil {
+    row := self.row32(k)
+    for l := range row {
+      if self.colMajor {
+        row[l] += float32(eps * dw[l][k])
+      } else {
+        row[l] += float32(eps * dw[k][l])
+      }
+    }
+    return
+  }
+  row := self.row(k)
+  for l := range row {
+    if self.colMajor {
+      row[l] += eps * dw[l][k]
+    } else {
+      row[l] += eps * dw[k][l]
+    }
+  }
+}
@@ -0,0 +1,80 @@
+package rbm
+
+import (
+  "math/bits"
+)
+
+// Semantic hashing (Salakhutdinov & Hinton, 2009): the hidden probabilities
+// of an item thresholded at 1/2 are a deterministic binary code, and items
+// with codes a few bits apart are similar, so a Hamming ball around a query's
+// code retrieves related items in time independent of the corpus size. Codes
+// come out closer to binary, and lose less in the thresholding, when the
+// model is fine-tuned as an autoencoder with noise injected into the code
+// layer, see Autoencoder.SetCodeNoise. Index searches codes by Hamming
+// distance.
+
+// The probabilities p thresholded at 1/2, packed 64 to a word.
+func PackCode(p []float64) Bitset {
+  code := NewBitset(len(p))
+  for j, pj := range p {
+    if pj > 0.5 {
+      code.Set(j, true)
+    }
+  }
+  return code
+}
+
+// Number of units in which self and other differ. Panics unless they have
+// the same number of words.
+func (self Bitset) Hamming(other Bitset) int {
+  checkBits("code", other, len(self) * 64)
+  n := 0
+  for k, w := range self {
+    n += bits.OnesCount64(w ^ other[k])
+  }
+  return n
+}
+
+// The binary code of v, E[h | v] thresholded at 1/2.
+func (self *RBM) BinaryCode(v []int) Bitset {
+  return PackCode(self.hiddenMeans(toFloats(v)))
+}
+func (self *RBM) BinaryCodeFloat(v []float64) Bitset {
+  return PackCode(self.hiddenMeans(v))
+}
+
+// Binary codes of every example, computed like Transform.
+func (self *RBM) BinaryCodes(vs [][]int) []Bitset {
+  return packCodes(self.Transform(vs))
+}
+func (self *RBM) BinaryCodesFloat(vs [][]float64) []Bitset {
+  return packCodes(self.TransformFloat(vs))
+}
+
+func packCodes(ps [][]float64) []Bitset {
+  codes := make([]Bitset, len(ps))
+  for n, p := range ps {
+    codes[n] = PackCode(p)
+  }
+  return codes
+}
+
+// Adds Gaussian noise of standard deviation sigma to the inputs of the code
+// layer during fine-tuning, which forces the codes towards 0 or 1 to get
+// through it, so that they survive thresholding (about 4 for semantic
+// hashing); 0, the default, turns it off. Encoding is never noisy. Panics if
+// sigma is negative.
+func (self *Autoencoder) SetCodeNoise(sigma float64) {
+  if sigma < 0 {
+    panic("rbm: negative code noise")
+  }
+  self.codeNoise = sigma
+}
+
+// The binary code of x, the code layer thresholded at 1/2.
+func (self *Autoencoder) BinaryCode(x []int) Bitset {
+  return PackCode(self.Encode(x))
+}
+func (self *Autoencoder) BinaryCodeFloat(x []float64) Bitset {
+  return PackCode(self.EncodeFloat(x))
+}
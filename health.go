@@ -0,0 +1,81 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Probabilities within this distance of 0 or 1 count as saturated.
+const saturationTolerance = 0.01
+
+// Diagnostics of one hidden unit over a data set. A unit that is almost never
+// on is dead and one that is almost always on carries no information either;
+// both usually call for a smaller learning rate or sparsity regularization
+// (WithSparsity). Saturated units learn slowly, as their gradients vanish.
+type UnitHealth struct {
+  MeanActivation float64 // mean p(h_j on | v) over the data
+  WeightNorm float64     // L2 norm of the unit's weights
+  Saturated float64      // fraction of examples with p(h_j on | v) within 0.01 of 0 or 1
+}
+
+// Health of every hidden unit over data, which should be (a sample of) the
+// training set. "On" means h_j > 0 for NReLU units.
+func (self *RBM) HiddenUnitHealth(data [][]int) []UnitHealth {
+  f := make([][]float64, len(data))
+  for n, v := range data {
+    f[n] = toFloats(v)
+  }
+  return self.HiddenUnitHealthFloat(f)
+}
+// Same as HiddenUnitHealth for real-valued data.
+func (self *RBM) HiddenUnitHealthFloat(data [][]float64) []UnitHealth {
+  health := make([]UnitHealth, self.m)
+  for j := range health {
+    sq := 0.0
+    for i := 0; i < self.d; i++ {
+      sq += self.weight(i, j) * self.weight(i, j)
+    }
+    health[j].WeightNorm = math.Sqrt(sq)
+  }
+  if len(data) == 0 {
+    return health
+  }
+  for _, v := range data {
+    checkLength("visible", len(v), self.d)
+    for j, x := range self.hiddenInputs(v) {
+      p := self.hiddenOnProbability(x)
+      health[j].MeanActivation += p
+      if p < saturationTolerance || p > 1 - saturationTolerance {
+        health[j].Saturated++
+      }
+    }
+  }
+  N := float64(len(data))
+  for j := range health {
+    health[j].MeanActivation /= N
+    health[j].Saturated /= N
+  }
+  return health
+}
+
+// Units whose mean activation is below threshold (dead) and above
+// 1 - threshold (always on).
+func UnhealthyUnits(health []UnitHealth, threshold float64) (dead, alwaysOn []int) {
+  for j, h := range health {
+    if h.MeanActivation < threshold {
+      dead = append(dead, j)
+    } else if h.MeanActivation > 1 - threshold {
+      alwaysOn = append(alwaysOn, j)
+    }
+  }
+  return
+}
+
+// A callback for WithCallback or WithEpochCallback that computes the health
+// of the hidden units over data and passes it to fn, e.g. to log the number
+// of dead units as training goes. It never stops training.
+func HealthCallback(data [][]int, fn func(status TrainingStatus, health []UnitHealth)) Callback {
+  return func(status TrainingStatus) bool {
+    fn(status, status.Model.HiddenUnitHealth(data))
+    return true
+  }
+}
@@ -0,0 +1,63 @@
+package rbm
+
+import (
+  "sync"
+)
+
+// circular buffer of the most recent hidden activation vectors
+type activationHistory struct {
+  mu sync.Mutex
+  buf [][]float64
+  next int
+  full bool
+}
+
+func (self *activationHistory) record(h []float64) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  self.buf[self.next] = h
+  self.next = (self.next + 1) % len(self.buf)
+  if self.next == 0 {
+    self.full = true
+  }
+}
+
+func (self *activationHistory) snapshot() [][]float64 {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  var hs [][]float64
+  if self.full {
+    hs = append(hs, self.buf[self.next:]...)
+  }
+  hs = append(hs, self.buf[:self.next]...)
+  out := make([][]float64, len(hs))
+  for k, h := range hs {
+    out[k] = append([]float64(nil), h...)
+  }
+  return out
+}
+
+// Records the last bufferSize hidden vectors produced by SampleHiddenLayer or
+// HiddenLayerExpectation. A bufferSize <= 0 disables recording. Calling it
+// again discards anything already buffered.
+func (self *RBM) SetActivationHistory(bufferSize int) {
+  if bufferSize <= 0 {
+    self.history = nil
+    return
+  }
+  self.history = &activationHistory{buf: make([][]float64, bufferSize)}
+}
+
+// Buffered hidden activations, oldest first.
+func (self *RBM) GetActivationHistory() [][]float64 {
+  if self.history == nil {
+    return nil
+  }
+  return self.history.snapshot()
+}
+
+func (self *RBM) recordActivation(h []float64) {
+  if self.history != nil {
+    self.history.record(h)
+  }
+}
@@ -0,0 +1,116 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "sort"
+)
+
+// How an Index compares hidden representations.
+type Metric int
+
+const (
+  Cosine  Metric = iota // 1 - cosine similarity of the hidden probabilities
+  Hamming               // Hamming distance of the binary codes
+)
+
+// One search result: the position of a corpus item in the index and its
+// distance to the query, smaller being more similar.
+type Neighbor struct {
+  Item int
+  Distance float64
+}
+
+// Nearest-neighbor search over the hidden representations of a corpus, for
+// retrieving the items most similar to a query in the space the model learned
+// (semantic hashing, when the codes are compared). Every item is stored as
+// its hidden probabilities E[h | v] and as its binary code, the
+// probabilities thresholded at 1/2, packed 64 units to a word. Searches scan
+// the whole index: O(N m) for Cosine, O(N m / 64) for Hamming.
+type Index struct {
+  rbm *RBM
+  features [][]float64
+  codes []Bitset
+}
+
+// An index of corpus under model. The model shouldn't change afterwards, or
+// queries and the stored items are encoded differently.
+func NewIndex(model *RBM, corpus [][]int) *Index {
+  self := &Index{rbm: model}
+  self.add(model.Transform(corpus))
+  return self
+}
+// Same as NewIndex for real-valued visible data.
+func NewIndexFloat(model *RBM, corpus [][]float64) *Index {
+  self := &Index{rbm: model}
+  self.add(model.TransformFloat(corpus))
+  return self
+}
+
+func (self *Index) add(features [][]float64) {
+  for _, f := range features {
+    self.features = append(self.features, f)
+    self.codes = append(self.codes, PackCode(f))
+  }
+}
+
+// Adds v to the index and returns its position.
+func (self *Index) Add(v []int) int {
+  self.add([][]float64{self.rbm.hiddenMeans(toFloats(v))})
+  return len(self.features) - 1
+}
+func (self *Index) AddFloat(v []float64) int {
+  self.add([][]float64{self.rbm.hiddenMeans(v)})
+  return len(self.features) - 1
+}
+
+// Number of items in the index.
+func (self *Index) Len() int {
+  return len(self.features)
+}
+
+// The k items nearest to query under metric, nearest first, ties broken by
+// position. Fewer are returned if the index holds fewer. Panics if k is
+// negative.
+func (self *Index) Search(query []int, k int, metric Metric) []Neighbor {
+  return self.SearchFloat(toFloats(query), k, metric)
+}
+func (self *Index) SearchFloat(query []float64, k int, metric Metric) []Neighbor {
+  if k < 0 {
+    panic(fmt.Sprintf("rbm: invalid number of neighbors %d", k))
+  }
+  q := self.rbm.hiddenMeans(query)
+  out := make([]Neighbor, len(self.features))
+  switch metric {
+  case Cosine:
+    qn := norm(q)
+    for n, f := range self.features {
+      out[n] = Neighbor{n, 1 - dot(q, f) / math.Max(qn * norm(f), 1e-300)}
+    }
+  case Hamming:
+    code := PackCode(q)
+    for n, c := range self.codes {
+      out[n] = Neighbor{n, float64(code.Hamming(c))}
+    }
+  default:
+    panic(fmt.Sprintf("rbm: unknown metric %d", metric))
+  }
+  sort.SliceStable(out, func(x, y int) bool {
+    return out[x].Distance < out[y].Distance
+  })
+  if k < len(out) {
+    out = out[:k]
+  }
+  return out
+}
+
+func dot(x, y []float64) float64 {
+  s := 0.0
+  for k := range x {
+    s += x[k] * y[k]
+  }
+  return s
+}
+func norm(x []float64) float64 {
+  return math.Sqrt(dot(x, x))
+}
@@ -0,0 +1,93 @@
+package rbm
+
+import (
+  "math"
+)
+
+// How WithWeightInit draws the connection weights.
+type WeightInit int
+
+const (
+  ZeroInit WeightInit = iota // all weights 0 (the default)
+  GaussianInit               // N(0, 0.01^2), as recommended by Hinton's practical guide
+  XavierInit                 // uniform in +-sqrt(6 / (d + m)) (Glorot & Bengio)
+)
+
+// Redraws the weights with the given scheme, using the model's generator.
+// Biases are left alone; see InitFromData.
+func WithWeightInit(init WeightInit) Option {
+  return func(self *RBM) {
+    self.initWeights(init)
+  }
+}
+
+func (self *RBM) initWeights(init WeightInit) {
+  limit := math.Sqrt(6 / float64(self.d + self.m))
+  for i := 0; i < self.d; i++ {
+    for j := 0; j < self.m; j++ {
+      switch init {
+      case GaussianInit:
+        self.setWeight(i, j, 0.01 * normal(self.r))
+      case XavierInit:
+        self.setWeight(i, j, limit * (2 * uniform(self.r) - 1))
+      default:
+        self.setWeight(i, j, 0)
+      }
+    }
+  }
+}
+
+// Sets the visible biases so that, with zero weights, each unit's marginal
+// matches data: log(p_i / (1 - p_i)) for binary units, log p_i within softmax
+// groups, the mean for Gaussian units, its log for Poisson units and the logit
+// of mean / trials for Binomial ones. Proportions are add-one smoothed so
+// constant pixels get large but finite biases.
+func (self *RBM) InitFromData(data [][]int) {
+  self.initBiases(len(data), func(n int) []float64 { return toFloats(data[n]) })
+}
+// Same as InitFromData for real-valued visible data.
+func (self *RBM) InitFromDataFloat(data [][]float64) {
+  self.initBiases(len(data), func(n int) []float64 { return data[n] })
+}
+
+func (self *RBM) initBiases(N int, example func(n int) []float64) {
+  if N == 0 {
+    return
+  }
+  copy(self.a, self.dataBiases(N, example))
+}
+
+// Visible biases of the independent model fitted to the data marginals.
+func (self *RBM) dataBiases(N int, example func(n int) []float64) []float64 {
+  mean := make([]float64, self.d)
+  for n := 0; n < N; n++ {
+    for i, vi := range example(n) {
+      mean[i] += vi
+    }
+  }
+  a := make([]float64, self.d)
+  for i := range mean {
+    switch self.visibleType {
+    case Gaussian:
+      a[i] = mean[i] / float64(N)
+      continue
+    case Poisson:
+      // log of the smoothed mean count
+      a[i] = math.Log((mean[i] + 1) / (float64(N) + 1))
+      continue
+    case Binomial:
+      n := float64(self.binomialTrials())
+      p := (mean[i] + 1) / (n * float64(N) + 2)
+      a[i] = math.Log(p / (1 - p))
+      continue
+    }
+    // add-one smoothing keeps the logits finite
+    p := (mean[i] + 1) / (float64(N) + 2)
+    if self.inSoftmax(i) {
+      a[i] = math.Log(p)
+    } else {
+      a[i] = math.Log(p / (1 - p))
+    }
+  }
+  return a
+}
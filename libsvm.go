@@ -0,0 +1,125 @@
+package rbm
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+type libsvmRow struct {
+  label float64
+  index []int // 0-based
+  value []float64
+}
+
+// Reads a libsvm/svmlight file ("label index:value ...", 1-based indices,
+// '#' comments, qid tokens ignored) as dense real-valued rows of length
+// numFeatures, or of the largest index present if numFeatures is 0.
+func LoadLibSVM(path string, numFeatures int) (x [][]float64, labels []float64, err error) {
+  rows, d, err := loadLibSVM(path)
+  if err != nil {
+    return nil, nil, err
+  }
+  if numFeatures == 0 {
+    numFeatures = d
+  } else if d > numFeatures {
+    return nil, nil, fmt.Errorf("rbm: feature index %d exceeds %d features", d, numFeatures)
+  }
+  x = make([][]float64, len(rows))
+  labels = make([]float64, len(rows))
+  for n, row := range rows {
+    x[n] = make([]float64, numFeatures)
+    for k, i := range row.index {
+      x[n][i] = row.value[k]
+    }
+    labels[n] = row.label
+  }
+  return x, labels, nil
+}
+
+// Same as LoadLibSVM, but returns each row as the sorted indices of its
+// nonzero features, the sparse binary form taken by TrainSparse, along with
+// the number of features (the largest index present).
+func LoadLibSVMSparse(path string) (active [][]int, labels []float64, numFeatures int, err error) {
+  rows, d, err := loadLibSVM(path)
+  if err != nil {
+    return nil, nil, 0, err
+  }
+  active = make([][]int, len(rows))
+  labels = make([]float64, len(rows))
+  for n, row := range rows {
+    for k, i := range row.index {
+      if row.value[k] != 0 {
+        active[n] = append(active[n], i)
+      }
+    }
+    labels[n] = row.label
+  }
+  return active, labels, d, nil
+}
+
+func loadLibSVM(path string) ([]libsvmRow, int, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, 0, err
+  }
+  defer f.Close()
+  return readLibSVM(f)
+}
+
+func readLibSVM(r io.Reader) (rows []libsvmRow, numFeatures int, err error) {
+  s := bufio.NewScanner(r)
+  s.Buffer(make([]byte, 64 * 1024), 64 * 1024 * 1024)
+  for line := 1; s.Scan(); line++ {
+    text := s.Text()
+    if k := strings.IndexByte(text, '#'); k >= 0 {
+      text = text[:k]
+    }
+    fields := strings.Fields(text)
+    if len(fields) == 0 {
+      continue
+    }
+    var row libsvmRow
+    if row.label, err = strconv.ParseFloat(fields[0], 64); err != nil {
+      return nil, 0, fmt.Errorf("rbm: line %d: bad label %q", line, fields[0])
+    }
+    for _, field := range fields[1:] {
+      colon := strings.IndexByte(field, ':')
+      if colon < 0 {
+        return nil, 0, fmt.Errorf("rbm: line %d: bad feature %q", line, field)
+      }
+      if field[:colon] == "qid" {
+        continue
+      }
+      i, err1 := strconv.Atoi(field[:colon])
+      x, err2 := strconv.ParseFloat(field[colon + 1:], 64)
+      if err1 != nil || err2 != nil || i < 1 {
+        return nil, 0, fmt.Errorf("rbm: line %d: bad feature %q", line, field)
+      }
+      row.index = append(row.index, i - 1)
+      row.value = append(row.value, x)
+      if i > numFeatures {
+        numFeatures = i
+      }
+    }
+    if !sort.IntsAreSorted(row.index) {
+      sort.Sort(byIndex(row))
+    }
+    rows = append(rows, row)
+  }
+  return rows, numFeatures, s.Err()
+}
+
+// sorts the features of a row by index
+type byIndex libsvmRow
+
+func (r byIndex) Len() int           { return len(r.index) }
+func (r byIndex) Less(a, b int) bool { return r.index[a] < r.index[b] }
+func (r byIndex) Swap(a, b int) {
+  r.index[a], r.index[b] = r.index[b], r.index[a]
+  r.value[a], r.value[b] = r.value[b], r.value[a]
+}
@@ -0,0 +1,22 @@
+//go:build !gonum
+
+package rbm
+
+// Hidden inputs b + W'v of every row of vs. Build with -tags gonum to do the
+// whole batch in one BLAS matrix multiply.
+func (self *RBM) hiddenInputsBatch(vs [][]float64) [][]float64 {
+  xs := make([][]float64, len(vs))
+  for n, v := range vs {
+    xs[n] = self.hiddenInputs(v)
+  }
+  return xs
+}
+
+// Visible inputs a + Wh of every row of hs.
+func (self *RBM) visibleInputsBatch(hs [][]float64) [][]float64 {
+  xs := make([][]float64, len(hs))
+  for n, h := range hs {
+    xs[n] = self.visibleInputs(h)
+  }
+  return xs
+}
@@ -0,0 +1,84 @@
+//go:build gonum
+
+package rbm
+
+import (
+  "gonum.org/v1/gonum/mat"
+)
+
+const batchChunk = 1024
+
+// Weights as a d x m matrix, a view of the flat weight storage (float32
+// weights are converted, O(dm), which the batch multiplies below amortize).
+func (self *RBM) weightMatrix() mat.Matrix {
+  rows, cols := self.d, self.m
+  if self.colMajor {
+    rows, cols = self.m, self.d
+  }
+  flat := self.w
+  if self.w32 != nil {
+    flat = make([]float64, len(self.w32))
+    for k, x := range self.w32 {
+      flat[k] = float64(x)
+    }
+  }
+  W := mat.NewDense(rows, cols, flat)
+  if self.colMajor {
+    return W.T()
+  }
+  return W
+}
+
+// Rows of xs stacked into an N x len(xs[0]) matrix.
+func stack(xs [][]float64) *mat.Dense {
+  flat := make([]float64, 0, len(xs) * len(xs[0]))
+  for _, x := range xs {
+    flat = append(flat, x...)
+  }
+  return mat.NewDense(len(xs), len(xs[0]), flat)
+}
+
+// Rows of X plus bias, as slices.
+func unstack(X *mat.Dense, bias []float64) [][]float64 {
+  N, k := X.Dims()
+  xs := make([][]float64, N)
+  for n := range xs {
+    xs[n] = append([]float64(nil), X.RawRowView(n)...)
+    for j := 0; j < k; j++ {
+      xs[n][j] += bias[j]
+    }
+  }
+  return xs
+}
+
+// Hidden inputs b + W'v of every row of vs, as the matrix multiply V W.
+func (self *RBM) hiddenInputsBatch(vs [][]float64) [][]float64 {
+  for _, v := range vs {
+    checkLength("visible", len(v), self.d)
+  }
+  return batchMul(vs, self.weightMatrix(), self.b)
+}
+
+// Visible inputs a + Wh of every row of hs, as the matrix multiply H W'.
+func (self *RBM) visibleInputsBatch(hs [][]float64) [][]float64 {
+  for _, h := range hs {
+    checkLength("hidden", len(h), self.m)
+  }
+  return batchMul(hs, self.weightMatrix().T(), self.a)
+}
+
+// Rows of xs times W plus bias, in chunks of batchChunk rows so a whole
+// dataset never has to be stacked at once.
+func batchMul(xs [][]float64, W mat.Matrix, bias []float64) [][]float64 {
+  out := make([][]float64, 0, len(xs))
+  for lo := 0; lo < len(xs); lo += batchChunk {
+    hi := lo + batchChunk
+    if hi > len(xs) {
+      hi = len(xs)
+    }
+    var X mat.Dense
+    X.Mul(stack(xs[lo:hi]), W)
+    out = append(out, unstack(&X, bias)...)
+  }
+  return out
+}
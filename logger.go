@@ -0,0 +1,50 @@
+package rbm
+
+import (
+  "fmt"
+)
+
+// Destination of the verbose training output. *log.Logger satisfies it.
+type Logger interface {
+  Printf(format string, v ...interface{})
+}
+
+const defaultLogInterval = 1000
+
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, v ...interface{}) {
+  fmt.Printf(format, v...)
+}
+
+// Sends verbose training output to l instead of stdout; nil restores stdout.
+func WithLogger(l Logger) TrainOption {
+  return func(self *Trainer) {
+    self.logger = l
+  }
+}
+
+// Number of iterations between verbose progress lines (default 1000).
+func WithLogInterval(iters int) TrainOption {
+  return func(self *Trainer) {
+    if iters < 1 {
+      iters = defaultLogInterval
+    }
+    self.logEvery = iters
+  }
+}
+
+func (self *Trainer) logf(format string, v ...interface{}) {
+  if self.logger == nil {
+    stdoutLogger{}.Printf(format, v...)
+    return
+  }
+  self.logger.Printf(format, v...)
+}
+
+func (self *Trainer) logInterval() int {
+  if self.logEvery < 1 {
+    return defaultLogInterval
+  }
+  return self.logEvery
+}
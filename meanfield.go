@@ -0,0 +1,23 @@
+package rbm
+
+// Mean-field Gibbs iteration: like a Gibbs chain, but every step propagates
+// the conditional means E[h | v] and E[v | h] instead of samples, so the
+// result is deterministic given the start. iters = 1 is Reconstruct; more
+// iterations move v towards a fixed point of the model, which denoises more
+// strongly than a single pass without the noise of sampling.
+func (self *RBM) MeanField(v []int, iters int) []float64 {
+  return self.MeanFieldFloat(toFloats(v), iters)
+}
+func (self *RBM) MeanFieldFloat(v []float64, iters int) []float64 {
+  p := append([]float64(nil), v...)
+  for t := 0; t < iters; t++ {
+    p = self.visibleMeans(self.hiddenMeans(p))
+  }
+  return p
+}
+
+// Mean-field counterpart of GenerateVisibleFloat: iters mean-field steps from
+// a random visible vector. Only the start is random.
+func (self *RBM) GenerateMeanField(iters int) []float64 {
+  return self.MeanFieldFloat(self.randomVisible(), iters)
+}
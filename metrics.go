@@ -0,0 +1,39 @@
+package rbm
+
+// Sum over units of log p(v_i | v_-i), computed exactly from the free energy
+// difference of flipping each unit. Flipping unit i only shifts the hidden
+// inputs by +-w_i, so the whole sum costs O(d m) rather than d free energies.
+func (self *RBM) logPseudoLikelihood(v []float64) float64 {
+  x := self.hiddenInputs(v)
+  pl := 0.0
+  for i := 0; i < self.d; i++ {
+    pl += self.logConditional(v, x, i)
+  }
+  return pl
+}
+
+// log p(v_i | v_-i) given the hidden inputs x of v
+func (self *RBM) logConditional(v, x []float64, i int) float64 {
+  delta := 1 - 2 * v[i]
+  // F(v with unit i flipped) - F(v)
+  dF := -self.a[i] * delta
+  for j := 0; j < self.m; j++ {
+    dF -= softplus(x[j] + delta * self.weight(i, j)) - softplus(x[j])
+  }
+  // log p(v_i | v_-i) = log sigmoid(dF)
+  return -softplus(-dF)
+}
+
+// Mean log pseudo-likelihood sum_i log p(v_i | v_-i) of the examples in data,
+// a tractable stand-in for the log-likelihood that should rise as training
+// progresses. Assumes binary visible units.
+func (self *RBM) PseudoLikelihood(data [][]int) float64 {
+  if len(data) == 0 {
+    return 0
+  }
+  pl := 0.0
+  for _, v := range data {
+    pl += self.logPseudoLikelihood(toFloats(v))
+  }
+  return pl / float64(len(data))
+}
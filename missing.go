@@ -0,0 +1,90 @@
+package rbm
+
+import (
+  "context"
+  "fmt"
+)
+
+// Marks a missing entry of an integer training example for TrainMissing.
+const Missing = -1
+
+// Training on incomplete data: the missing visible units of an example are
+// unobserved, so each time the example is drawn they are filled in by cdt
+// Gibbs cycles with the observed units clamped, starting from a random
+// visible vector, and the completed vector is used in the positive phase. The
+// negative phase then runs from it as usual, all units free.
+
+// Same as Train for examples with entries equal to Missing where the value is
+// unknown. Returns an error, before taking any step, if an example doesn't
+// have one value per visible unit or has no observed value.
+func (self *Trainer) TrainMissing(v [][]int, iters int, verbose bool) (History, error) {
+  if err := self.model.checkInts(v); err != nil {
+    return History{}, err
+  }
+  data := make([][]float64, len(v))
+  observed := make([][]bool, len(v))
+  for n, vn := range v {
+    data[n], observed[n] = make([]float64, self.model.d), make([]bool, self.model.d)
+    for i, x := range vn {
+      if x != Missing {
+        data[n][i], observed[n][i] = float64(x), true
+      }
+    }
+  }
+  return self.TrainMasked(data, observed, iters, verbose)
+}
+
+// Same as TrainMissing for real-valued data with the observed entries of
+// example n flagged in observed[n]; the values of the others are ignored.
+func (self *Trainer) TrainMasked(v [][]float64, observed [][]bool, iters int, verbose bool) (History, error) {
+  return self.TrainMaskedContext(context.Background(), v, observed, iters, verbose)
+}
+// Same as TrainMasked, stopping once ctx is done, see TrainContext.
+func (self *Trainer) TrainMaskedContext(ctx context.Context, v [][]float64, observed [][]bool, iters int, verbose bool) (History, error) {
+  if err := self.model.checkMasked(v, observed); err != nil {
+    return History{}, err
+  }
+  return self.train(ctx, len(v), func(n int) []float64 { return self.model.completeExample(v[n], observed[n]) }, iters, verbose)
+}
+
+// v with its unobserved units sampled given the observed ones
+func (self *RBM) completeExample(v []float64, observed []bool) []float64 {
+  start := self.randomVisible()
+  complete := true
+  for i, o := range observed {
+    if o {
+      start[i] = v[i]
+    } else {
+      complete = false
+    }
+  }
+  if complete {
+    return start
+  }
+  c := self.NewGibbsChainFloat(start)
+  c.SetClamp(observed)
+  c.Step(self.cdt)
+  return c.v
+}
+
+func (self *RBM) checkMasked(v [][]float64, observed [][]bool) error {
+  if len(observed) != len(v) {
+    return fmt.Errorf("rbm: %d observation masks for %d examples", len(observed), len(v))
+  }
+  if err := self.checkFloats(v); err != nil {
+    return err
+  }
+  for n, o := range observed {
+    if len(o) != self.d {
+      return fmt.Errorf("rbm: mask %d has length %d, want %d", n, len(o), self.d)
+    }
+    seen := false
+    for _, oi := range o {
+      seen = seen || oi
+    }
+    if !seen {
+      return fmt.Errorf("rbm: example %d has no observed value", n)
+    }
+  }
+  return nil
+}
@@ -0,0 +1,400 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+)
+
+// Output function of a Network layer.
+type Activation int
+
+const (
+  Sigmoid Activation = iota
+  Softmax                 // over the whole layer
+  Linear
+  NReLUMean               // the mean of an NReLU unit, see nreluMean
+)
+
+type netLayer struct {
+  w [][]float64 // inputs x outputs, like an RBM's d x m weights
+  b []float64
+  act Activation
+  velW [][]float64 // momentum
+  velB []float64
+}
+
+func newNetLayer(in, out int, act Activation) *netLayer {
+  return &netLayer{w: zeros(in, out), b: make([]float64, out), act: act}
+}
+
+// A layer initialized from the weights and hidden biases of rbm, computing
+// E[h | v] for its hidden unit type.
+func rbmLayer(rbm *RBM) *netLayer {
+  act := Sigmoid
+  switch rbm.hiddenType {
+  case Binary:
+  case NReLU:
+    act = NReLUMean
+  default:
+    panic(fmt.Sprintf("rbm: no network activation for hidden unit type %d", rbm.hiddenType))
+  }
+  l := newNetLayer(rbm.d, rbm.m, act)
+  for i := 0; i < rbm.d; i++ {
+    for j := 0; j < rbm.m; j++ {
+      l.w[i][j] = rbm.weight(i, j)
+    }
+  }
+  copy(l.b, rbm.b)
+  return l
+}
+
+func (self *netLayer) forward(x []float64) []float64 {
+  return self.activate(self.inputs(x))
+}
+// b + x W
+func (self *netLayer) inputs(x []float64) []float64 {
+  y := append([]float64(nil), self.b...)
+  for i, xi := range x {
+    if xi == 0 {
+      continue
+    }
+    for j, wij := range self.w[i] {
+      y[j] += xi * wij
+    }
+  }
+  return y
+}
+// Applies the activation to the inputs y in place.
+func (self *netLayer) activate(y []float64) []float64 {
+  switch self.act {
+  case Sigmoid:
+    for j := range y {
+      y[j] = expit(y[j])
+    }
+  case Softmax:
+    y = normalizeLog(y)
+  case NReLUMean:
+    for j := range y {
+      y[j] = nreluMean(y[j])
+    }
+  }
+  return y
+}
+
+// The derivative of nreluMean at x: with s = sqrt(sigmoid(x)) and z = x / s
+// it is Phi(z) + phi(z) ds/dx, the terms in dz cancelling.
+func nreluMeanSlope(x float64) float64 {
+  p := expit(x)
+  s := math.Sqrt(p)
+  z := x / s
+  return 0.5 * math.Erfc(-z / math.Sqrt2) + math.Exp(-z * z / 2) / math.Sqrt(2 * math.Pi) * p * (1 - p) / (2 * s)
+}
+
+// Mini-batch gradient descent settings of backpropagation fine-tuning, which
+// can be changed between calls.
+type BackpropSettings struct {
+  LearningRate float64 // default 0.1
+  BatchSize int        // default 10
+  Momentum float64
+  WeightDecay float64  // L2 penalty on the weights
+  Logger Logger        // of the verbose output, stdout if nil
+  LogInterval int      // epochs between verbose lines, default 1
+}
+
+var defaultBackprop = BackpropSettings{LearningRate: 0.1, BatchSize: 10}
+
+func (self *BackpropSettings) batchSize() int {
+  if self.BatchSize < 1 {
+    return 1
+  }
+  return self.BatchSize
+}
+
+// Logs the mean loss of epoch (0-based) if verbose and one is due.
+func (self *BackpropSettings) logEpoch(verbose bool, epoch int, loss float64) {
+  every := self.LogInterval
+  if every < 1 {
+    every = 1
+  }
+  if !verbose || (epoch + 1) % every != 0 {
+    return
+  }
+  format := "Fine-tuning epoch: %d, loss: %.4f\n"
+  if self.Logger == nil {
+    stdoutLogger{}.Printf(format, epoch + 1, loss)
+  } else {
+    self.Logger.Printf(format, epoch + 1, loss)
+  }
+}
+
+// Descends the weight gradient dw summed over a batch of N examples, with
+// momentum through *vel (allocated on first use).
+func (self *BackpropSettings) stepWeights(w, dw [][]float64, vel *[][]float64, N float64) {
+  if self.Momentum != 0 && *vel == nil {
+    *vel = zeros(len(w), len(w[0]))
+  }
+  eps := self.LearningRate / N
+  for i := range w {
+    for j := range w[i] {
+      step := -eps * (dw[i][j] + N * self.WeightDecay * w[i][j])
+      if *vel != nil {
+        (*vel)[i][j] = self.Momentum * (*vel)[i][j] + step
+        step = (*vel)[i][j]
+      }
+      w[i][j] += step
+    }
+  }
+}
+// Same as stepWeights for biases, which are not decayed.
+func (self *BackpropSettings) stepBiases(b, db []float64, vel *[]float64, N float64) {
+  if self.Momentum != 0 && *vel == nil {
+    *vel = make([]float64, len(b))
+  }
+  eps := self.LearningRate / N
+  for j := range b {
+    step := -eps * db[j]
+    if *vel != nil {
+      (*vel)[j] = self.Momentum * (*vel)[j] + step
+      step = (*vel)[j]
+    }
+    b[j] += step
+  }
+}
+
+// Loss of the network output y against the target t under the
+// output activation: cross-entropy for softmax and sigmoid outputs, half the
+// squared error for linear ones.
+func outputLoss(act Activation, y, t []float64) float64 {
+  loss := 0.0
+  for j := range y {
+    switch act {
+    case Linear:
+      loss += (y[j] - t[j]) * (y[j] - t[j]) / 2
+    case Softmax:
+      if t[j] != 0 {
+        loss -= t[j] * math.Log(math.Max(y[j], 1e-300))
+      }
+    default:
+      loss -= t[j] * math.Log(math.Max(y[j], 1e-300)) + (1 - t[j]) * math.Log(math.Max(1 - y[j], 1e-300))
+    }
+  }
+  return loss
+}
+
+// Feed-forward network, typically unrolled from pretrained RBMs and then
+// fine-tuned with backpropagation.
+type Network struct {
+  BackpropSettings
+  layers []*netLayer
+  r *rand.Rand
+}
+
+func newNetwork(layers []*netLayer, r *rand.Rand) *Network {
+  return &Network{BackpropSettings: defaultBackprop, layers: layers, r: r}
+}
+
+func (self *Network) NumInputs() int {
+  return len(self.layers[0].w)
+}
+
+func (self *Network) NumOutputs() int {
+  return len(self.layers[len(self.layers) - 1].b)
+}
+
+// Number of weight layers.
+func (self *Network) NumLayers() int {
+  return len(self.layers)
+}
+
+// Weights (inputs x outputs) and biases of layer k, bottom first, as copies.
+func (self *Network) Layer(k int) (w [][]float64, b []float64) {
+  l := self.layers[k]
+  w = zeros(len(l.w), len(l.b))
+  for i := range l.w {
+    copy(w[i], l.w[i])
+  }
+  return w, append([]float64(nil), l.b...)
+}
+
+// The activations of every layer for input x, x itself first.
+func (self *Network) activations(x []float64) [][]float64 {
+  checkLength("input", len(x), self.NumInputs())
+  a := [][]float64{x}
+  for _, l := range self.layers {
+    a = append(a, l.forward(a[len(a) - 1]))
+  }
+  return a
+}
+
+// The output of the network for input x.
+func (self *Network) ForwardFloat(x []float64) []float64 {
+  a := self.activations(x)
+  return a[len(a) - 1]
+}
+func (self *Network) Forward(x []int) []float64 {
+  return self.ForwardFloat(toFloats(x))
+}
+
+// Backpropagates the error of the output for x against target, accumulating
+// the gradients of the loss into dw and db. With the output activation
+// matched to the loss (softmax or sigmoid with cross-entropy, linear with
+// squared error) the output delta is simply output - target. Returns the
+// loss.
+func (self *Network) backprop(x, target []float64, dw [][][]float64, db [][]float64) float64 {
+  a := self.activations(x)
+  out := a[len(a) - 1]
+  delta := make([]float64, len(out))
+  for j, y := range out {
+    delta[j] = y - target[j]
+  }
+  loss := outputLoss(self.layers[len(self.layers) - 1].act, out, target)
+  for k := len(self.layers) - 1; k >= 0; k-- {
+    l, in := self.layers[k], a[k]
+    for i, xi := range in {
+      if xi == 0 {
+        continue
+      }
+      for j, dj := range delta {
+        dw[k][i][j] += xi * dj
+      }
+    }
+    for j, dj := range delta {
+      db[k][j] += dj
+    }
+    if k == 0 {
+      break
+    }
+    lower := self.layers[k - 1]
+    var x []float64 // inputs of the layer below, for slopes not given by its output
+    if lower.act == NReLUMean {
+      x = lower.inputs(a[k - 1])
+    }
+    below := make([]float64, len(in))
+    for i := range below {
+      s := 0.0
+      for j, dj := range delta {
+        s += l.w[i][j] * dj
+      }
+      switch lower.act {
+      case Sigmoid:
+        s *= in[i] * (1 - in[i])
+      case NReLUMean:
+        s *= nreluMeanSlope(x[i])
+      }
+      below[i] = s
+    }
+    delta = below
+  }
+  return loss
+}
+
+// Runs epochs passes of mini-batch backpropagation over the inputs x and
+// targets t in random order. Returns the mean loss of the last epoch.
+func (self *Network) train(x, t [][]float64, epochs int, verbose bool) float64 {
+  batchSize := self.batchSize()
+  dw := make([][][]float64, len(self.layers))
+  db := make([][]float64, len(self.layers))
+  loss := 0.0
+  for epoch := 0; epoch < epochs; epoch++ {
+    loss = 0
+    order := perm(self.r, len(x))
+    for start := 0; start < len(order); start += batchSize {
+      end := start + batchSize
+      if end > len(order) {
+        end = len(order)
+      }
+      for k, l := range self.layers {
+        dw[k], db[k] = zeros(len(l.w), len(l.b)), make([]float64, len(l.b))
+      }
+      for _, n := range order[start:end] {
+        loss += self.backprop(x[n], t[n], dw, db)
+      }
+      self.update(dw, db, float64(end - start))
+    }
+    loss /= float64(len(x))
+    self.logEpoch(verbose, epoch, loss)
+  }
+  return loss
+}
+
+// Applies the loss gradients dw and db summed over a batch of N examples.
+func (self *Network) update(dw [][][]float64, db [][]float64, N float64) {
+  for k, l := range self.layers {
+    self.stepWeights(l.w, dw[k], &l.velW, N)
+    self.stepBiases(l.b, db[k], &l.velB, N)
+  }
+}
+
+// Fine-tunes a classifier (see DBN.Classifier) on the labelled examples for
+// epochs passes, minimizing the cross-entropy of the softmax output. With
+// verbose set the mean loss of each epoch is logged. Returns an error,
+// without training, if there is no data, an example has the wrong length or
+// a label is out of range.
+func (self *Network) FineTune(v [][]int, labels []int, epochs int, verbose bool) error {
+  x := make([][]float64, len(v))
+  for n := range v {
+    x[n] = toFloats(v[n])
+  }
+  return self.FineTuneFloat(x, labels, epochs, verbose)
+}
+// Same as FineTune for real-valued inputs.
+func (self *Network) FineTuneFloat(x [][]float64, labels []int, epochs int, verbose bool) error {
+  K := self.NumOutputs()
+  if len(labels) != len(x) {
+    return fmt.Errorf("rbm: %d labels for %d examples", len(labels), len(x))
+  }
+  if err := checkInputs(x, self.NumInputs(), epochs); err != nil {
+    return err
+  }
+  t := make([][]float64, len(x))
+  for n, y := range labels {
+    if y < 0 || y >= K {
+      return fmt.Errorf("rbm: label %d of example %d out of range [0, %d)", y, n, K)
+    }
+    t[n] = make([]float64, K)
+    t[n][y] = 1
+  }
+  self.train(x, t, epochs, verbose)
+  return nil
+}
+
+func checkInputs(x [][]float64, want, epochs int) error {
+  for n, xn := range x {
+    if len(xn) != want {
+      return fmt.Errorf("rbm: example %d has length %d, want %d", n, len(xn), want)
+    }
+  }
+  return checkTrainingSize(len(x), epochs)
+}
+
+// p(y | v) for every label y of a classifier.
+func (self *Network) PredictProba(v []int) []float64 {
+  return self.Forward(v)
+}
+
+// The most probable label of v.
+func (self *Network) Predict(v []int) int {
+  p := self.Forward(v)
+  best := 0
+  for y, py := range p {
+    if py > p[best] {
+      best = y
+    }
+  }
+  return best
+}
+
+// Fraction of the examples whose label is predicted correctly.
+func (self *Network) Accuracy(v [][]int, labels []int) float64 {
+  if len(v) == 0 {
+    return 0
+  }
+  correct := 0
+  for n := range v {
+    if self.Predict(v[n]) == labels[n] {
+      correct++
+    }
+  }
+  return float64(correct) / float64(len(v))
+}
@@ -0,0 +1,35 @@
+package rbm
+
+import (
+  "math"
+  "testing"
+)
+
+// The unrolled layers compute what the pretrained RBMs do.
+func TestClassifierMatchesLayers(t *testing.T) {
+  for _, hidden := range []UnitType{Binary, NReLU} {
+    dbn := NewDBN([]int{6, 4, 3}, 1, nil, WithSeed(1), WithHiddenUnits(hidden), WithWeightInit(GaussianInit))
+    net := dbn.Classifier(2)
+    v := []int{1, 0, 1, 1, 0, 1}
+    a := net.activations(toFloats(v))
+    h := toFloats(v)
+    for k, layer := range dbn.Layers() {
+      h = layer.HiddenLayerExpectationFloat(h)
+      for j := range h {
+        if math.Abs(a[k + 1][j] - h[j]) > 1e-12 {
+          t.Fatalf("hidden type %d, layer %d: unit %d computes %v, the RBM %v", hidden, k, j, a[k + 1][j], h[j])
+        }
+      }
+    }
+  }
+}
+
+func TestNReLUMeanSlope(t *testing.T) {
+  for _, x := range []float64{-3, -0.5, 0.1, 2} {
+    const eps = 1e-6
+    numeric := (nreluMean(x + eps) - nreluMean(x - eps)) / (2 * eps)
+    if math.Abs(nreluMeanSlope(x) - numeric) > 1e-6 {
+      t.Errorf("slope at %v: %v, numerically %v", x, nreluMeanSlope(x), numeric)
+    }
+  }
+}
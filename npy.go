@@ -0,0 +1,200 @@
+package rbm
+
+import (
+  "archive/zip"
+  "bufio"
+  "encoding/binary"
+  "fmt"
+  "io"
+  "math"
+  "math/rand"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+var (
+  npyDescr = regexp.MustCompile(`'descr'\s*:\s*'([<>|=])([fiu])(\d)'`)
+  npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+  npyShape = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// Reads one array in NumPy's .npy format, returning its elements in row-major
+// (C) order converted to float64, and its shape. Float, signed and unsigned
+// integer dtypes of either byte order are supported.
+func ReadNpy(r io.Reader) (data []float64, shape []int, err error) {
+  br := bufio.NewReader(r)
+  magic := make([]byte, 8)
+  if _, err = io.ReadFull(br, magic); err != nil {
+    return nil, nil, err
+  }
+  if string(magic[:6]) != "\x93NUMPY" {
+    return nil, nil, fmt.Errorf("rbm: not a .npy file")
+  }
+  var headerLen uint32
+  if magic[6] == 1 {
+    var n uint16
+    err = binary.Read(br, binary.LittleEndian, &n)
+    headerLen = uint32(n)
+  } else {
+    err = binary.Read(br, binary.LittleEndian, &headerLen)
+  }
+  if err != nil {
+    return nil, nil, err
+  }
+  header := make([]byte, headerLen)
+  if _, err = io.ReadFull(br, header); err != nil {
+    return nil, nil, err
+  }
+  descr := npyDescr.FindStringSubmatch(string(header))
+  fortran := npyFortran.FindStringSubmatch(string(header))
+  shapeStr := npyShape.FindStringSubmatch(string(header))
+  if descr == nil || fortran == nil || shapeStr == nil {
+    return nil, nil, fmt.Errorf("rbm: unsupported .npy header %q", header)
+  }
+  size := 1
+  for _, dim := range strings.Split(shapeStr[1], ",") {
+    if dim = strings.TrimSpace(dim); dim == "" {
+      continue
+    }
+    n, err := strconv.Atoi(dim)
+    if err != nil {
+      return nil, nil, fmt.Errorf("rbm: bad .npy shape %q", shapeStr[1])
+    }
+    shape = append(shape, n)
+    size *= n
+  }
+  var order binary.ByteOrder = binary.LittleEndian
+  if descr[1] == ">" {
+    order = binary.BigEndian
+  }
+  width, _ := strconv.Atoi(descr[3])
+  decode, err := npyDecoder(descr[2], width, order)
+  if err != nil {
+    return nil, nil, err
+  }
+  buf := make([]byte, width)
+  data = make([]float64, size)
+  for k := range data {
+    if _, err = io.ReadFull(br, buf); err != nil {
+      return nil, nil, err
+    }
+    data[k] = decode(buf)
+  }
+  if fortran[1] == "True" && len(shape) > 1 {
+    data = fortranToC(data, shape)
+  }
+  return data, shape, nil
+}
+
+func npyDecoder(kind string, width int, order binary.ByteOrder) (func([]byte) float64, error) {
+  switch kind + strconv.Itoa(width) {
+  case "f8":
+    return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }, nil
+  case "f4":
+    return func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }, nil
+  case "i8":
+    return func(b []byte) float64 { return float64(int64(order.Uint64(b))) }, nil
+  case "i4":
+    return func(b []byte) float64 { return float64(int32(order.Uint32(b))) }, nil
+  case "i1":
+    return func(b []byte) float64 { return float64(int8(b[0])) }, nil
+  case "u8":
+    return func(b []byte) float64 { return float64(order.Uint64(b)) }, nil
+  case "u4":
+    return func(b []byte) float64 { return float64(order.Uint32(b)) }, nil
+  case "u1":
+    return func(b []byte) float64 { return float64(b[0]) }, nil
+  }
+  return nil, fmt.Errorf("rbm: unsupported .npy dtype %s%d", kind, width)
+}
+
+// column-major elements of an array of the given shape in row-major order
+func fortranToC(data []float64, shape []int) []float64 {
+  out := make([]float64, len(data))
+  idx := make([]int, len(shape))
+  for k := range data {
+    // k is the Fortran offset of idx, the first index varying fastest
+    c := 0
+    for a := range shape {
+      c = c * shape[a] + idx[a]
+    }
+    out[c] = data[k]
+    for a := 0; a < len(shape); a++ {
+      if idx[a]++; idx[a] < shape[a] {
+        break
+      }
+      idx[a] = 0
+    }
+  }
+  return out
+}
+
+// Names of the arrays holding the parameters in an .npz archive.
+type NpzKeys struct {
+  Weights, VisibleBiases, HiddenBiases string
+  // weights stored hidden x visible (m x d) rather than d x m
+  Transposed bool
+}
+
+// The attributes of a scikit-learn BernoulliRBM saved with
+// np.savez(path, components_=..., intercept_visible_=..., intercept_hidden_=...).
+var SklearnKeys = NpzKeys{"components_", "intercept_visible_", "intercept_hidden_", true}
+
+// Builds an RBM from the weights and biases in the .npz archive at path,
+// e.g. one pretrained in Python. The size is taken from the bias vectors;
+// cdt, r and opts are as for NewRBM.
+func LoadNpz(path string, keys NpzKeys, cdt int, r *rand.Rand, opts ...Option) (*RBM, error) {
+  z, err := zip.OpenReader(path)
+  if err != nil {
+    return nil, err
+  }
+  defer z.Close()
+  arrays := make(map[string][]float64)
+  shapes := make(map[string][]int)
+  for _, f := range z.File {
+    name := strings.TrimSuffix(f.Name, ".npy")
+    if name != keys.Weights && name != keys.VisibleBiases && name != keys.HiddenBiases {
+      continue
+    }
+    rc, err := f.Open()
+    if err != nil {
+      return nil, err
+    }
+    arrays[name], shapes[name], err = ReadNpy(rc)
+    rc.Close()
+    if err != nil {
+      return nil, fmt.Errorf("rbm: %s: %v", f.Name, err)
+    }
+  }
+  for _, key := range []string{keys.Weights, keys.VisibleBiases, keys.HiddenBiases} {
+    if _, ok := arrays[key]; !ok {
+      return nil, fmt.Errorf("rbm: %s has no array %q", path, key)
+    }
+  }
+  a, b, flat := arrays[keys.VisibleBiases], arrays[keys.HiddenBiases], arrays[keys.Weights]
+  d, m := len(a), len(b)
+  want := []int{d, m}
+  if keys.Transposed {
+    want = []int{m, d}
+  }
+  if s := shapes[keys.Weights]; len(s) != 2 || s[0] != want[0] || s[1] != want[1] {
+    return nil, fmt.Errorf("rbm: weights have shape %v, want %v", s, want)
+  }
+  w := make([][]float64, d)
+  for i := range w {
+    w[i] = make([]float64, m)
+    for j := range w[i] {
+      if keys.Transposed {
+        w[i][j] = flat[j * d + i]
+      } else {
+        w[i][j] = flat[i * m + j]
+      }
+    }
+  }
+  self := NewRBM(d, m, cdt, r, opts...)
+  self.SetWeights(w)
+  self.SetVisibleBiases(a)
+  self.SetHiddenBiases(b)
+  return self, nil
+}
@@ -0,0 +1,268 @@
+package rbm
+
+import (
+  "bytes"
+  "encoding/gob"
+  "math"
+)
+
+// Turns gradients into parameter updates. Step receives the ascent direction
+// of every gradient step (dw is d x m) and the current learning rate, and
+// overwrites the gradients in place with the change to add to the
+// parameters. Optimizers keep per-parameter state between steps, so each
+// trainer needs its own instance; Reset discards that state, which happens
+// whenever the model's parameters are replaced (Unmarshal, ReadFrom,
+// LoadState, early stopping). Optimizers that implement
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler, as the ones in
+// this package do, have their state saved by SaveState.
+type Optimizer interface {
+  Step(epsilon float64, dw [][]float64, da, db []float64)
+  Reset()
+}
+
+// Replaces the built-in update (plain SGD, or classical momentum with
+// WithMomentum) with opt; nil restores it. See Optimizer.
+func WithOptimizer(opt Optimizer) TrainOption {
+  return func(self *Trainer) {
+    self.optimizer = opt
+  }
+}
+
+// Calls fn on every entry of the gradient in a fixed order, storing its
+// result in place; k is the entry's position in that order.
+func eachGradient(dw [][]float64, da, db []float64, fn func(k int, g float64) float64) {
+  k := 0
+  for i := range dw {
+    for j := range dw[i] {
+      dw[i][j] = fn(k, dw[i][j])
+      k++
+    }
+  }
+  for i := range da {
+    da[i] = fn(k, da[i])
+    k++
+  }
+  for j := range db {
+    db[j] = fn(k, db[j])
+    k++
+  }
+}
+
+func numGradients(dw [][]float64, da, db []float64) int {
+  n := len(da) + len(db)
+  for i := range dw {
+    n += len(dw[i])
+  }
+  return n
+}
+
+// Reallocates the state vectors when they don't match n parameters.
+func optimizerState(n int, state ...*[]float64) {
+  for _, s := range state {
+    if len(*s) != n {
+      *s = make([]float64, n)
+    }
+  }
+}
+
+// Gob form of the built-in optimizers' state.
+type optimizerSnapshot struct {
+  T int
+  State [][]float64
+}
+
+func marshalOptimizer(t int, state ...[]float64) ([]byte, error) {
+  var buf bytes.Buffer
+  err := gob.NewEncoder(&buf).Encode(optimizerSnapshot{t, state})
+  return buf.Bytes(), err
+}
+
+func unmarshalOptimizer(data []byte, t *int, state ...*[]float64) error {
+  var snap optimizerSnapshot
+  if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+    return err
+  }
+  for k, s := range state {
+    *s = nil
+    if k < len(snap.State) {
+      *s = snap.State[k]
+    }
+  }
+  if t != nil {
+    *t = snap.T
+  }
+  return nil
+}
+
+// Plain stochastic gradient ascent: each parameter moves by epsilon times its
+// gradient.
+type SGD struct{}
+
+func NewSGD() *SGD {
+  return &SGD{}
+}
+
+func (self *SGD) Step(epsilon float64, dw [][]float64, da, db []float64) {
+  eachGradient(dw, da, db, func(k int, g float64) float64 {
+    return epsilon * g
+  })
+}
+
+func (self *SGD) Reset() {}
+
+func (self *SGD) MarshalBinary() ([]byte, error) {
+  return marshalOptimizer(0)
+}
+
+func (self *SGD) UnmarshalBinary(data []byte) error {
+  return unmarshalOptimizer(data, nil)
+}
+
+// SGD with classical momentum mu: the update is mu times the previous one
+// plus epsilon times the gradient, as with WithMomentum.
+type Momentum struct {
+  mu float64
+  v []float64
+}
+
+func NewMomentum(mu float64) *Momentum {
+  return &Momentum{mu: mu}
+}
+
+func (self *Momentum) Step(epsilon float64, dw [][]float64, da, db []float64) {
+  optimizerState(numGradients(dw, da, db), &self.v)
+  eachGradient(dw, da, db, func(k int, g float64) float64 {
+    self.v[k] = self.mu * self.v[k] + epsilon * g
+    return self.v[k]
+  })
+}
+
+func (self *Momentum) Reset() {
+  self.v = nil
+}
+
+func (self *Momentum) MarshalBinary() ([]byte, error) {
+  return marshalOptimizer(0, self.v)
+}
+
+func (self *Momentum) UnmarshalBinary(data []byte) error {
+  return unmarshalOptimizer(data, nil, &self.v)
+}
+
+// AdaGrad (Duchi et al., 2011): each parameter's step is divided by the root
+// of the sum of its squared gradients so far, so frequently updated ones slow
+// down. Learning rates around 0.01-0.1 are typical.
+type AdaGrad struct {
+  g2 []float64
+}
+
+func NewAdaGrad() *AdaGrad {
+  return &AdaGrad{}
+}
+
+func (self *AdaGrad) Step(epsilon float64, dw [][]float64, da, db []float64) {
+  optimizerState(numGradients(dw, da, db), &self.g2)
+  eachGradient(dw, da, db, func(k int, g float64) float64 {
+    self.g2[k] += g * g
+    return epsilon * g / (math.Sqrt(self.g2[k]) + 1e-8)
+  })
+}
+
+func (self *AdaGrad) Reset() {
+  self.g2 = nil
+}
+
+func (self *AdaGrad) MarshalBinary() ([]byte, error) {
+  return marshalOptimizer(0, self.g2)
+}
+
+func (self *AdaGrad) UnmarshalBinary(data []byte) error {
+  return unmarshalOptimizer(data, nil, &self.g2)
+}
+
+// RMSProp (Tieleman & Hinton, 2012): like AdaGrad but with an exponential
+// moving average of the squared gradients (typically decay = 0.9), so the
+// steps don't shrink forever. Learning rates around 0.001 are typical.
+type RMSProp struct {
+  decay float64
+  g2 []float64
+}
+
+func NewRMSProp(decay float64) *RMSProp {
+  return &RMSProp{decay: decay}
+}
+
+func (self *RMSProp) Step(epsilon float64, dw [][]float64, da, db []float64) {
+  optimizerState(numGradients(dw, da, db), &self.g2)
+  rho := self.decay
+  eachGradient(dw, da, db, func(k int, g float64) float64 {
+    self.g2[k] = rho * self.g2[k] + (1 - rho) * g * g
+    return epsilon * g / (math.Sqrt(self.g2[k]) + 1e-8)
+  })
+}
+
+func (self *RMSProp) Reset() {
+  self.g2 = nil
+}
+
+func (self *RMSProp) MarshalBinary() ([]byte, error) {
+  return marshalOptimizer(0, self.g2)
+}
+
+func (self *RMSProp) UnmarshalBinary(data []byte) error {
+  return unmarshalOptimizer(data, nil, &self.g2)
+}
+
+// Adam (Kingma & Ba, 2015): bias-corrected moving averages of the gradient
+// (rate beta1) and of its square (rate beta2), the step being their ratio.
+// The usual settings are beta1 = 0.9, beta2 = 0.999 with learning rates
+// around 0.001.
+type Adam struct {
+  beta1, beta2 float64
+  t int
+  m, v []float64
+}
+
+func NewAdam(beta1, beta2 float64) *Adam {
+  return &Adam{beta1: beta1, beta2: beta2}
+}
+
+func (self *Adam) Step(epsilon float64, dw [][]float64, da, db []float64) {
+  n := numGradients(dw, da, db)
+  if len(self.m) != n {
+    self.t = 0
+  }
+  optimizerState(n, &self.m, &self.v)
+  self.t++
+  b1, b2 := self.beta1, self.beta2
+  c1 := 1 - math.Pow(b1, float64(self.t))
+  c2 := 1 - math.Pow(b2, float64(self.t))
+  eachGradient(dw, da, db, func(k int, g float64) float64 {
+    self.m[k] = b1 * self.m[k] + (1 - b1) * g
+    self.v[k] = b2 * self.v[k] + (1 - b2) * g * g
+    return epsilon * (self.m[k] / c1) / (math.Sqrt(self.v[k] / c2) + 1e-8)
+  })
+}
+
+func (self *Adam) Reset() {
+  self.t, self.m, self.v = 0, nil, nil
+}
+
+func (self *Adam) MarshalBinary() ([]byte, error) {
+  return marshalOptimizer(self.t, self.m, self.v)
+}
+
+func (self *Adam) UnmarshalBinary(data []byte) error {
+  return unmarshalOptimizer(data, &self.t, &self.m, &self.v)
+}
+
+// Number of parameter-sized state vectors opt keeps.
+func optimizerBuffers(opt Optimizer) int {
+  switch opt.(type) {
+  case *Momentum, *AdaGrad, *RMSProp:
+    return 1
+  case *Adam:
+    return 2
+  }
+  return 0
+}
@@ -0,0 +1,53 @@
+package rbm
+
+import (
+  "encoding"
+  "testing"
+)
+
+func flatGradient(dw [][]float64, da, db []float64) []float64 {
+  var g []float64
+  eachGradient(dw, da, db, func(k int, x float64) float64 {
+    g = append(g, x)
+    return x
+  })
+  return g
+}
+
+// An optimizer restored from its binary state makes the same next step.
+func TestOptimizerState(t *testing.T) {
+  gradient := func(s float64) ([][]float64, []float64, []float64) {
+    return [][]float64{{s, -2 * s}, {0.5 * s, s}}, []float64{-s, 3 * s}, []float64{s, 0.25 * s}
+  }
+  for name, opts := range map[string]func() Optimizer{
+    "SGD": func() Optimizer { return NewSGD() },
+    "Momentum": func() Optimizer { return NewMomentum(0.9) },
+    "AdaGrad": func() Optimizer { return NewAdaGrad() },
+    "RMSProp": func() Optimizer { return NewRMSProp(0.9) },
+    "Adam": func() Optimizer { return NewAdam(0.9, 0.999) },
+  } {
+    opt := opts()
+    for k := 1; k <= 3; k++ {
+      dw, da, db := gradient(float64(k))
+      opt.Step(0.1, dw, da, db)
+    }
+    state, err := opt.(encoding.BinaryMarshaler).MarshalBinary()
+    if err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    resumed := opts()
+    if err := resumed.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    dw, da, db := gradient(4)
+    rdw, rda, rdb := gradient(4)
+    opt.Step(0.1, dw, da, db)
+    resumed.Step(0.1, rdw, rda, rdb)
+    want := flatGradient(dw, da, db)
+    for k, g := range flatGradient(rdw, rda, rdb) {
+      if g != want[k] {
+        t.Errorf("%s: resumed step %d is %g, want %g", name, k, g, want[k])
+      }
+    }
+  }
+}
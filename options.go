@@ -0,0 +1,115 @@
+package rbm
+
+import (
+  "fmt"
+  "math/rand"
+)
+
+// Configures an RBM at construction (see NewRBM) or later with SetOptions.
+type Option func(*RBM)
+
+// Number of Gibbs steps k in the negative phase of CD-k (default 1); also the
+// steps per update of PCD and tempering chains. Panics if k is below 1.
+func WithCDK(k int) Option {
+  if k < 1 {
+    panic(fmt.Sprintf("rbm: cdt must be at least 1, got %d", k))
+  }
+  return func(self *RBM) {
+    self.cdt = k
+  }
+}
+
+// Stores the weights hidden-unit major (m x d) so that GetHiddenProbability
+// reads contiguous memory. Worth it for large d, where the hidden layer
+// computations dominate sampling and training. Existing weights are kept.
+func WithColumnMajor() Option {
+  return func(self *RBM) {
+    self.setLayout(true)
+  }
+}
+
+// Stores the weights visible-unit major (d x m), the default.
+func WithRowMajor() Option {
+  return func(self *RBM) {
+    self.setLayout(false)
+  }
+}
+
+// Step size of the gradient updates (default 0.05).
+func WithLearningRate(epsilon float64) TrainOption {
+  return func(self *Trainer) {
+    self.epsilon = epsilon
+  }
+}
+
+// Number of examples averaged into each gradient step of Train (default 1).
+func WithBatchSize(n int) TrainOption {
+  return func(self *Trainer) {
+    if n < 1 {
+      n = 1
+    }
+    self.batchSize = n
+  }
+}
+
+// Classical momentum: each update adds mu times the previous update to the
+// current gradient step (default 0, plain SGD). Typical values are 0.5 early
+// in training and 0.9 later. Has no effect with WithOptimizer.
+func WithMomentum(mu float64) TrainOption {
+  return func(self *Trainer) {
+    self.momentum = mu
+  }
+}
+
+// L2 weight decay: each step also moves w by -epsilon * lambda * w
+// (default 0). Biases are not decayed.
+func WithWeightDecay(lambda float64) TrainOption {
+  return func(self *Trainer) {
+    self.weightDecay = lambda
+  }
+}
+
+// Distribution of the visible units given the hidden layer (default Binary).
+// Gaussian visibles model real-valued data, which should be standardized to
+// zero mean and unit variance per unit; train them with the Float variants
+// such as TrainFloat. Poisson and Binomial visibles model counts, see
+// WithBinomialTrials.
+func WithVisibleUnits(t UnitType) Option {
+  return func(self *RBM) {
+    self.visibleType = t
+  }
+}
+
+// Distribution of the hidden units given the visible layer (default Binary).
+// NReLU units are noisy rectified linear units, an approximation to many
+// tied binary units that can represent intensities instead of just on/off.
+// With NReLU hiddens the model usually needs a smaller learning rate.
+func WithHiddenUnits(t UnitType) Option {
+  return func(self *RBM) {
+    self.hiddenType = t
+  }
+}
+
+// Sparsity regularization: keeps an exponentially decaying average q_j of
+// each hidden unit's activation (q <- decay * q + (1 - decay) * batch mean)
+// and pushes it towards target with strength cost. Hinton's practical guide
+// suggests a target of 0.01 - 0.1 and decay 0.9 - 0.99. cost 0 disables it.
+func WithSparsity(target, cost, decay float64) TrainOption {
+  return func(self *Trainer) {
+    self.sparsityTarget, self.sparsityCost, self.sparsityDecay = target, cost, decay
+    self.hiddenActivity = nil
+  }
+}
+
+// Random source for sampling and training, e.g. one shared with other code.
+// Panics if r is nil: use WithSeed for a reproducible model, or
+// WithRandomSeed for a fresh seed.
+func WithRand(r *rand.Rand) Option {
+  if r == nil {
+    panic("rbm: nil *rand.Rand, use WithSeed for a seeded source")
+  }
+  return func(self *RBM) {
+    self.r, self.src = r, nil
+    self.seed, self.seeded = 0, false
+  }
+}
@@ -0,0 +1,158 @@
+package rbm
+
+import (
+  "math/rand"
+  "sync"
+)
+
+// Splits the per-example gradients of each mini-batch across n goroutines
+// (default 1, sequential). Results are reproducible for a given seed whatever
+// the goroutine scheduling: see sumGradients.
+func WithWorkers(n int) Option {
+  return func(self *RBM) {
+    if n < 1 {
+      n = 1
+    }
+    self.workers = n
+  }
+}
+
+// Sums the gradients (and data expectations) of the examples vs[lo:hi]
+// against the shared negative phase, each gradient multiplied by its weight
+// unless weights is nil, storing each example's unweighted hidden bias
+// gradient in hDelta. Example n samples from its own stream (base, n), so
+// its gradient doesn't depend on which goroutine computes it or on how the
+// batch is split; only the rounding of the sums does.
+func (self *Trainer) sumGradients(vs [][]float64, weights []float64, lo, hi int, negV, negH [][]float64, hDelta [][]float64, base uint64) (dw [][]float64, da, db, hSum []float64) {
+  model := self.model
+  hSum = make([]float64, model.m)
+  ex := *model
+  ex.chain = model.newChainBuffers()
+  for n := lo; n < hi; n++ {
+    ex.r = rand.New(streamSource(base, n))
+    if self.dropout > 0 {
+      ex.dropMask, ex.dropScale = self.sampleDropoutMask(ex.r), 1 / (1 - self.dropout)
+    }
+    dwn, dan, dbn, hExp := ex.gradientFrom(vs[n], negV, negH)
+    if ex.dropMask != nil {
+      self.dropoutGradient(ex.dropMask, dwn, dbn)
+    }
+    hDelta[n] = dbn
+    if weights != nil {
+      dwn, dan, dbn = scaleGradient(dwn, dan, dbn, weights[n])
+    }
+    for j := 0; j < model.m; j++ {
+      hSum[j] += hExp[j]
+    }
+    if n == lo {
+      dw, da, db = dwn, dan, append([]float64(nil), dbn...)
+      continue
+    }
+    for i := 0; i < model.d; i++ {
+      da[i] += dan[i]
+      for j := 0; j < model.m; j++ {
+        dw[i][j] += dwn[i][j]
+      }
+    }
+    for j := 0; j < model.m; j++ {
+      db[j] += dbn[j]
+    }
+  }
+  return
+}
+
+// The gradient times s: dw and da scaled in place, db copied so that hDelta
+// keeps the unweighted one.
+func scaleGradient(dw [][]float64, da, db []float64, s float64) ([][]float64, []float64, []float64) {
+  for i := range dw {
+    da[i] *= s
+    for j := range dw[i] {
+      dw[i][j] *= s
+    }
+  }
+  scaled := make([]float64, len(db))
+  for j, g := range db {
+    scaled[j] = s * g
+  }
+  return dw, da, scaled
+}
+
+// Same as sumGradients over the whole batch, with contiguous chunks of it
+// handled concurrently by shallow copies of the model that share its
+// parameters (read-only here). The streams hang off one draw from the
+// model's generator per batch.
+func (self *Trainer) sumGradientsParallel(vs [][]float64, weights []float64, negV, negH [][]float64, hDelta [][]float64) (dw [][]float64, da, db, hSum []float64) {
+  model := self.model
+  base := uint64(int63(model.r))
+  W := model.workers
+  if W > len(vs) {
+    W = len(vs)
+  }
+  if W <= 1 {
+    return self.sumGradients(vs, weights, 0, len(vs), negV, negH, hDelta, base)
+  }
+  dws := make([][][]float64, W)
+  das := make([][]float64, W)
+  dbs := make([][]float64, W)
+  hSums := make([][]float64, W)
+  var wg sync.WaitGroup
+  for k := 0; k < W; k++ {
+    lo, hi := k * len(vs) / W, (k + 1) * len(vs) / W
+    wg.Add(1)
+    go func(k int) {
+      defer wg.Done()
+      dws[k], das[k], dbs[k], hSums[k] = self.sumGradients(vs, weights, lo, hi, negV, negH, hDelta, base)
+    }(k)
+  }
+  wg.Wait()
+  // reduce in worker order so the result doesn't depend on scheduling
+  dw, da, db, hSum = dws[0], das[0], dbs[0], hSums[0]
+  for k := 1; k < W; k++ {
+    for i := 0; i < model.d; i++ {
+      da[i] += das[k][i]
+      for j := 0; j < model.m; j++ {
+        dw[i][j] += dws[k][i][j]
+      }
+    }
+    for j := 0; j < model.m; j++ {
+      db[j] += dbs[k][j]
+      hSum[j] += hSums[k][j]
+    }
+  }
+  return
+}
+
+// Calls fn on up to self.workers contiguous chunks of [0, N) concurrently.
+func (self *RBM) parallelChunks(N int, fn func(lo, hi int)) {
+  W := self.workers
+  if W > N {
+    W = N
+  }
+  if W <= 1 {
+    fn(0, N)
+    return
+  }
+  var wg sync.WaitGroup
+  for k := 0; k < W; k++ {
+    wg.Add(1)
+    go func(lo, hi int) {
+      defer wg.Done()
+      fn(lo, hi)
+    }(k * N / W, (k + 1) * N / W)
+  }
+  wg.Wait()
+}
+
+// A view of the model that shares its parameters but samples from r, for
+// serving one model from many goroutines: each calls the sampling methods on
+// its own fork. Forks are for inference only; training either the model or a
+// fork while the others are in use is a data race. Panics if r is nil.
+func (self *RBM) Fork(r *rand.Rand) *RBM {
+  if r == nil {
+    panic("rbm: nil *rand.Rand, each fork needs its own source")
+  }
+  fork := *self
+  fork.r, fork.src, fork.dropMask = r, nil, nil
+  fork.seeded = false
+  return &fork
+}
@@ -0,0 +1,31 @@
+package rbm
+
+import (
+  "math"
+  "testing"
+)
+
+func trainWorkers(t *testing.T, workers int) *RBM {
+  t.Helper()
+  data := [][]int{{1, 0, 1, 0, 1, 0}, {0, 1, 0, 1, 0, 1}, {1, 1, 0, 0, 1, 1}, {0, 0, 1, 1, 0, 0}, {1, 0, 0, 1, 1, 0}}
+  tr := NewTrainer(New(6, 4, WithSeed(3), WithWorkers(workers)), WithBatchSize(5), WithPCD(2))
+  if _, err := tr.Train(data, 20, false); err != nil {
+    t.Fatal(err)
+  }
+  return tr.Model()
+}
+
+// Same seed, same weights: goroutine scheduling doesn't reach the result, and
+// splitting the batch differently only changes the rounding of the sums.
+func TestWorkersReproducible(t *testing.T) {
+  want := trainWorkers(t, 3)
+  sameModel(t, "3 workers", trainWorkers(t, 3), want)
+  got := trainWorkers(t, 1)
+  for i := 0; i < want.d; i++ {
+    for j := 0; j < want.m; j++ {
+      if d := math.Abs(got.weight(i, j) - want.weight(i, j)); d > 1e-9 {
+        t.Fatalf("w[%d][%d] off by %g between 1 and 3 workers", i, j, d)
+      }
+    }
+  }
+}
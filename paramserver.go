@@ -0,0 +1,199 @@
+package rbm
+
+import (
+  "fmt"
+  "net"
+  "net/rpc"
+  "sync"
+)
+
+// Wire format of a gradient pushed by a worker.
+type Gradient struct {
+  DW [][]float64
+  DA, DB []float64
+}
+
+// Central copy of the model for data-parallel SGD. Workers fetch the current
+// parameters, compute ParameterGradients on their own shard and push them
+// back; the server averages the pushed gradients and applies them every
+// applyEvery pushes, asynchronously by default or in lockstep rounds with
+// SetSynchronous. Workers in the same process may call GetParams and
+// PushGradient directly, remote ones go through a ParameterClient; see
+// Worker.
+type ParameterServer struct {
+  mu sync.Mutex
+  trainer *Trainer
+  rbm *RBM // the trainer's model
+  applyEvery int
+  synchronous bool
+  round int // updates applied so far
+  applied *sync.Cond // signalled after each update
+  pending int
+  sumW [][]float64
+  sumA, sumB []float64
+  listener net.Listener
+}
+
+// Starts serving the model of t over net/rpc on addr (e.g. ":0" for any free
+// port). Pushed gradients are applied with t's update rule at its base
+// learning rate.
+func NewParameterServer(addr string, t *Trainer) (*ParameterServer, error) {
+  self := &ParameterServer{trainer: t, rbm: t.model, applyEvery: 1}
+  self.applied = sync.NewCond(&self.mu)
+  self.reset()
+  server := rpc.NewServer()
+  if err := server.RegisterName("ParameterServer", &parameterService{self}); err != nil {
+    return nil, err
+  }
+  l, err := net.Listen("tcp", addr)
+  if err != nil {
+    return nil, err
+  }
+  self.listener = l
+  go server.Accept(l)
+  return self, nil
+}
+
+func (self *ParameterServer) reset() {
+  self.pending = 0
+  self.sumW = make([][]float64, self.rbm.d)
+  for i := 0; i < self.rbm.d; i++ {
+    self.sumW[i] = make([]float64, self.rbm.m)
+  }
+  self.sumA = make([]float64, self.rbm.d)
+  self.sumB = make([]float64, self.rbm.m)
+}
+
+// Address the server is listening on.
+func (self *ParameterServer) Addr() net.Addr {
+  return self.listener.Addr()
+}
+
+// Stops accepting connections.
+func (self *ParameterServer) Close() error {
+  return self.listener.Close()
+}
+
+// Number of pushed gradients averaged into each parameter update (default
+// 1), applied as they arrive: a worker may push gradients computed from
+// parameters a few updates old. Turns off synchronous mode.
+func (self *ParameterServer) SetApplyEvery(n int) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  if n < 1 {
+    n = 1
+  }
+  self.applyEvery, self.synchronous = n, false
+  self.applied.Broadcast()
+}
+
+// Synchronous SGD with numWorkers workers: each update averages one gradient
+// from every worker, and PushGradient blocks until the round's update is
+// applied, so the next GetParams sees it and no gradient is stale. A worker
+// that stops pushing stalls the others.
+func (self *ParameterServer) SetSynchronous(numWorkers int) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  if numWorkers < 1 {
+    numWorkers = 1
+  }
+  self.applyEvery, self.synchronous = numWorkers, true
+}
+
+// Number of parameter updates applied so far.
+func (self *ParameterServer) Updates() int {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  return self.round
+}
+
+// Copy of the current model, without a random source.
+func (self *ParameterServer) GetParams() (*RBM, error) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  return self.rbm.params().model(), nil
+}
+
+func (self *ParameterServer) PushGradient(dW [][]float64, dA, dB []float64) error {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  d, m := self.rbm.d, self.rbm.m
+  if len(dW) != d || len(dA) != d || len(dB) != m {
+    return fmt.Errorf("rbm: gradient dimensions do not match %d x %d model", d, m)
+  }
+  for i := 0; i < d; i++ {
+    if len(dW[i]) != m {
+      return fmt.Errorf("rbm: gradient row %d has length %d, want %d", i, len(dW[i]), m)
+    }
+  }
+  for i := 0; i < d; i++ {
+    self.sumA[i] += dA[i]
+    for j := 0; j < m; j++ {
+      self.sumW[i][j] += dW[i][j]
+    }
+  }
+  for j := 0; j < m; j++ {
+    self.sumB[j] += dB[j]
+  }
+  self.pending++
+  if self.pending >= self.applyEvery {
+    self.trainer.applyGradient(self.trainer.epsilon / float64(self.pending), self.sumW, self.sumA, self.sumB)
+    self.reset()
+    self.round++
+    self.applied.Broadcast()
+    return nil
+  }
+  for round := self.round; self.synchronous && self.round == round; {
+    self.applied.Wait()
+  }
+  return nil
+}
+
+// net/rpc adapter for ParameterServer.
+type parameterService struct {
+  ps *ParameterServer
+}
+
+func (self *parameterService) GetParams(_ int, reply *Params) error {
+  self.ps.mu.Lock()
+  defer self.ps.mu.Unlock()
+  *reply = *self.ps.rbm.params()
+  return nil
+}
+
+func (self *parameterService) PushGradient(g Gradient, _ *int) error {
+  return self.ps.PushGradient(g.DW, g.DA, g.DB)
+}
+
+// Remote handle on a ParameterServer.
+type ParameterClient struct {
+  client *rpc.Client
+}
+
+func DialParameterServer(addr string) (*ParameterClient, error) {
+  client, err := rpc.Dial("tcp", addr)
+  if err != nil {
+    return nil, err
+  }
+  return &ParameterClient{client}, nil
+}
+
+func (self *ParameterClient) GetParams() (*RBM, error) {
+  var p Params
+  if err := self.client.Call("ParameterServer.GetParams", 0, &p); err != nil {
+    return nil, err
+  }
+  if err := p.validate(); err != nil {
+    return nil, err
+  }
+  return p.model(), nil
+}
+
+func (self *ParameterClient) PushGradient(dW [][]float64, dA, dB []float64) error {
+  var ignored int
+  return self.client.Call("ParameterServer.PushGradient", Gradient{dW, dA, dB}, &ignored)
+}
+
+func (self *ParameterClient) Close() error {
+  return self.client.Close()
+}
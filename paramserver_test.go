@@ -0,0 +1,70 @@
+package rbm
+
+import (
+  "math"
+  "testing"
+)
+
+func constGradient(d, m int, x float64) ([][]float64, []float64, []float64) {
+  dw, da, db := zeros(d, m), make([]float64, d), make([]float64, m)
+  for i := range dw {
+    for j := range dw[i] {
+      dw[i][j] = x
+    }
+    da[i] = x
+  }
+  for j := range db {
+    db[j] = x
+  }
+  return dw, da, db
+}
+
+// Pushes over net/rpc are averaged applyEvery at a time and applied at the
+// trainer's learning rate.
+func TestParameterServerAverages(t *testing.T) {
+  ps, err := NewParameterServer("127.0.0.1:0", NewTrainer(New(3, 2, WithSeed(1)), WithLearningRate(0.5)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer ps.Close()
+  client, err := DialParameterServer(ps.Addr().String())
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer client.Close()
+  start, err := client.GetParams()
+  if err != nil {
+    t.Fatal(err)
+  }
+  ps.SetApplyEvery(2)
+  if err := client.PushGradient(constGradient(3, 2, 1)); err != nil {
+    t.Fatal(err)
+  }
+  if ps.Updates() != 0 {
+    t.Fatalf("%d updates after one push of two", ps.Updates())
+  }
+  if err := client.PushGradient(constGradient(3, 2, 3)); err != nil {
+    t.Fatal(err)
+  }
+  if ps.Updates() != 1 {
+    t.Fatalf("%d updates after two pushes", ps.Updates())
+  }
+  got, err := client.GetParams()
+  if err != nil {
+    t.Fatal(err)
+  }
+  // mean gradient 2 at rate 0.5
+  for i := 0; i < 3; i++ {
+    if d := got.a[i] - start.a[i]; math.Abs(d - 1) > 1e-12 {
+      t.Errorf("a[%d] moved by %g, want 1", i, d)
+    }
+    for j := 0; j < 2; j++ {
+      if d := got.weight(i, j) - start.weight(i, j); math.Abs(d - 1) > 1e-12 {
+        t.Errorf("w[%d][%d] moved by %g, want 1", i, j, d)
+      }
+    }
+  }
+  if err := client.PushGradient(constGradient(2, 2, 1)); err == nil {
+    t.Error("mismatched gradient accepted")
+  }
+}
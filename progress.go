@@ -0,0 +1,98 @@
+package rbm
+
+import (
+  "fmt"
+  "time"
+)
+
+// Progress of a training run, reported through WithProgress and in the
+// verbose output of Train and TrainEpochs.
+type Progress struct {
+  Iteration int                // gradient steps taken so far in this run
+  Iterations int               // gradient steps the run will take if not stopped
+  Epoch int                    // completed epochs, 0 outside TrainEpochs
+  Elapsed time.Duration
+  ETA time.Duration            // estimated time left at the current rate
+  Rate float64                 // gradient steps per second
+  ReconstructionError float64  // mean over the latest mini-batch
+}
+
+func (p Progress) String() string {
+  return fmt.Sprintf("%d/%d (%.1f it/s, elapsed %v, ETA %v), reconstruction error: %.4f",
+    p.Iteration, p.Iterations, p.Rate, roundDuration(p.Elapsed), roundDuration(p.ETA), p.ReconstructionError)
+}
+
+func roundDuration(d time.Duration) time.Duration {
+  switch {
+  case d >= time.Minute:
+    return d.Round(time.Second)
+  case d >= time.Second:
+    return d.Round(100 * time.Millisecond)
+  }
+  return d.Round(time.Millisecond)
+}
+
+type progressReporter struct {
+  every int
+  fn func(Progress)
+}
+
+// Calls fn with the progress of Train and TrainEpochs runs every `every`
+// gradient steps. fn runs on the training goroutine, so it should be quick.
+func WithProgress(every int, fn func(Progress)) TrainOption {
+  return func(self *Trainer) {
+    if every < 1 {
+      every = 1
+    }
+    self.progress = &progressReporter{every, fn}
+  }
+}
+
+// Same as WithProgress, sending each report on ch. Sends never block: a
+// report is dropped if ch isn't ready for it, so a slow UI can't stall
+// training. Use a buffered channel to keep the latest few.
+func WithProgressChan(every int, ch chan<- Progress) TrainOption {
+  return WithProgress(every, func(p Progress) {
+    select {
+    case ch <- p:
+    default:
+    }
+  })
+}
+
+// Removes the progress reporter.
+func WithoutProgress() TrainOption {
+  return func(self *Trainer) {
+    self.progress = nil
+  }
+}
+
+// Clock of one training run of total gradient steps.
+type progressMeter struct {
+  start time.Time
+  total int
+}
+
+func newProgressMeter(total int) progressMeter {
+  return progressMeter{time.Now(), total}
+}
+
+// Progress after it steps, with the reconstruction error on batch.
+func (self *Trainer) progressAt(pm progressMeter, it, epoch int, batch [][]float64) Progress {
+  p := Progress{Iteration: it, Iterations: pm.total, Epoch: epoch, Elapsed: time.Since(pm.start)}
+  if secs := p.Elapsed.Seconds(); secs > 0 {
+    p.Rate = float64(it) / secs
+  }
+  if it > 0 && pm.total > it {
+    p.ETA = time.Duration(float64(p.Elapsed) / float64(it) * float64(pm.total - it))
+  }
+  p.ReconstructionError = self.model.batchReconstructionError(batch)
+  return p
+}
+
+// Reports step it (1-based) to the progress reporter if one is due.
+func (self *Trainer) reportProgress(pm progressMeter, it, epoch int, batch [][]float64) {
+  if pr := self.progress; pr != nil && it % pr.every == 0 {
+    pr.fn(self.progressAt(pm, it, epoch, batch))
+  }
+}
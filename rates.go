@@ -0,0 +1,73 @@
+package rbm
+
+// Learning rate factors of the parameter groups, all 1 by default: the
+// learning rate (and its schedule) sets the overall step size, and these
+// scale the steps of the weights, visible biases and hidden biases relative
+// to it. Gaussian visible units often need steps one or two orders of
+// magnitude smaller than binary ones (Hinton, 2010, section 13.2), which
+// the gaussian factor applies to their weights and biases on top of the
+// others.
+type rateScales struct {
+  w, a, b float64
+  gaussian float64
+}
+
+func (self *Trainer) rateScales() *rateScales {
+  if self.rates == nil {
+    self.rates = &rateScales{1, 1, 1, 1}
+  }
+  return self.rates
+}
+
+// Multiplies the learning rate by weights for the weight updates, by visible
+// for the visible biases and by hidden for the hidden biases, e.g.
+// WithLearningRateScales(1, 1, 0.1) for slowly moving hidden biases. Applies
+// to the steps of any optimizer, and before momentum. Panics if a factor is
+// negative.
+func WithLearningRateScales(weights, visible, hidden float64) TrainOption {
+  if weights < 0 || visible < 0 || hidden < 0 {
+    panic("rbm: negative learning rate scale")
+  }
+  return func(self *Trainer) {
+    r := self.rateScales()
+    r.w, r.a, r.b = weights, visible, hidden
+  }
+}
+
+// Further multiplies the learning rate by s for the weights and biases of
+// Gaussian visible units (not those in softmax groups), e.g. 0.01. Panics if
+// s is negative.
+func WithGaussianRateScale(s float64) TrainOption {
+  if s < 0 {
+    panic("rbm: negative learning rate scale")
+  }
+  return func(self *Trainer) {
+    self.rateScales().gaussian = s
+  }
+}
+
+// Multiplies the steps (or gradients) dw, da and db, in place, by the
+// factors of their parameter groups.
+func (self *Trainer) scaleSteps(dw [][]float64, da, db []float64) {
+  r := self.rates
+  if r == nil {
+    return
+  }
+  for i := range dw {
+    sw, sa := r.w, r.a
+    if self.model.visibleType == Gaussian && !self.model.inSoftmax(i) {
+      sw, sa = sw * r.gaussian, sa * r.gaussian
+    }
+    if sw != 1 {
+      for j := range dw[i] {
+        dw[i][j] *= sw
+      }
+    }
+    da[i] *= sa
+  }
+  if r.b != 1 {
+    for j := range db {
+      db[j] *= r.b
+    }
+  }
+}
@@ -0,0 +1,249 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+)
+
+// A user's rating of an item, Value in 1..K.
+type Rating struct {
+  Item, Value int
+}
+
+// RBM for collaborative filtering (Salakhutdinov, Mnih & Hinton, 2007). Every
+// item is a K-way softmax visible unit, one state per rating value, and every
+// user gets an RBM over just the items they rated; the users' RBMs share the
+// weights and biases of the items they have in common. Training only ever
+// touches a user's rated items, so the missing ratings, usually the vast
+// majority, cost nothing. The visible unit of item i, rating k is unit
+// i K + k - 1 of the underlying RBM.
+type RatingsRBM struct {
+  rbm *RBM
+  trainer *Trainer
+  k int
+  levels []int // 0, ..., K - 1
+  users [][]Rating
+}
+
+// numItems items rated 1..numRatings and numHidden hidden units; the other
+// arguments are as for NewRBM. Panics unless there are at least two rating
+// values.
+func NewRatingsRBM(numItems, numRatings, numHidden, cdt int, r *rand.Rand, opts ...Option) *RatingsRBM {
+  if numRatings < 2 {
+    panic(fmt.Sprintf("rbm: a RatingsRBM needs at least two rating values, got %d", numRatings))
+  }
+  groups := make([][]int, numItems)
+  for i := range groups {
+    for k := 0; k < numRatings; k++ {
+      groups[i] = append(groups[i], i * numRatings + k)
+    }
+  }
+  levels := make([]int, numRatings)
+  for k := range levels {
+    levels[k] = k
+  }
+  opts = append([]Option{WithSoftmaxGroups(groups)}, opts...)
+  self := &RatingsRBM{
+    rbm: NewRBM(numItems * numRatings, numHidden, cdt, r, opts...),
+    k: numRatings,
+    levels: levels,
+  }
+  self.trainer = NewTrainer(self.rbm)
+  return self
+}
+
+// The underlying RBM over all items' softmax units.
+func (self *RatingsRBM) RBM() *RBM {
+  return self.rbm
+}
+
+// The trainer of the underlying RBM. Its learning rate (and schedule), batch
+// size and weight decay govern training.
+func (self *RatingsRBM) Trainer() *Trainer {
+  return self.trainer
+}
+
+func (self *RatingsRBM) NumItems() int {
+  return self.rbm.d / self.k
+}
+func (self *RatingsRBM) NumRatings() int {
+  return self.k
+}
+
+// Visible units that are on for the ratings.
+func (self *RatingsRBM) active(ratings []Rating) []int {
+  units := make([]int, len(ratings))
+  for n, r := range ratings {
+    units[n] = r.Item * self.k + r.Value - 1
+  }
+  return units
+}
+
+// p(h | ratings)
+func (self *RatingsRBM) hiddenMeans(ratings []Rating) []float64 {
+  return expitAll(self.rbm.hiddenInputsSparse(self.active(ratings)))
+}
+
+// Distribution of item's rating, 0-based, given the hidden layer.
+func (self *RatingsRBM) ratingProbs(item int, h []float64) []float64 {
+  p := make([]float64, self.k)
+  for k := range p {
+    u := item * self.k + k
+    p[k] = self.rbm.a[u]
+    for j, hj := range h {
+      p[k] += self.rbm.weight(u, j) * hj
+    }
+  }
+  softmax(p, self.levels, 1)
+  return p
+}
+
+// CD-k statistics of one user, accumulated into the rows of dw for the
+// visible units involved, da and db.
+func (self *RatingsRBM) gradient(ratings []Rating, dw map[int][]float64, da map[int]float64, db []float64) {
+  rbm := self.rbm
+  h0 := self.hiddenMeans(ratings)
+  v, h := ratings, h0
+  for t := 0; t < rbm.cdt; t++ {
+    hs := make([]float64, rbm.m)
+    for j, hj := range h {
+      hs[j] = float64(bernoulli(rbm.r, hj))
+    }
+    v = make([]Rating, len(ratings))
+    for n, r := range ratings {
+      p := self.ratingProbs(r.Item, hs)
+      rbm.sampleSoftmax(p, self.levels)
+      for k, pk := range p {
+        if pk == 1 {
+          v[n] = Rating{r.Item, k + 1}
+        }
+      }
+    }
+    h = self.hiddenMeans(v)
+  }
+  accumulate := func(units []int, hExp []float64, sign float64) {
+    for _, u := range units {
+      row := dw[u]
+      if row == nil {
+        row = make([]float64, rbm.m)
+        dw[u] = row
+      }
+      for j, hj := range hExp {
+        row[j] += sign * hj
+      }
+      da[u] += sign
+    }
+  }
+  accumulate(self.active(ratings), h0, 1)
+  accumulate(self.active(v), h, -1)
+  for j := range db {
+    db[j] += h0[j] - h[j]
+  }
+}
+
+func (self *RatingsRBM) checkRatings(users [][]Rating) error {
+  for u, ratings := range users {
+    for _, r := range ratings {
+      if r.Item < 0 || r.Item >= self.NumItems() {
+        return fmt.Errorf("rbm: user %d rated item %d, want 0 <= item < %d", u, r.Item, self.NumItems())
+      }
+      if r.Value < 1 || r.Value > self.k {
+        return fmt.Errorf("rbm: user %d rated item %d %d, want 1 <= rating <= %d", u, r.Item, r.Value, self.k)
+      }
+    }
+  }
+  return nil
+}
+
+// Trains with CD on iters mini-batches of users drawn at random from users,
+// user u's ratings being users[u], and keeps the ratings for PredictRating.
+// Each step updates only the weights and biases of the items rated in the
+// batch, with plain gradient steps: the trainer's momentum, optimizer and
+// clipping settings are ignored. Returns an error, without training, if
+// there are no users or a rating is out of range.
+func (self *RatingsRBM) Train(users [][]Rating, iters int, verbose bool) error {
+  rbm, tr := self.rbm, self.trainer
+  if err := self.checkRatings(users); err != nil {
+    return err
+  }
+  if err := checkTrainingSize(len(users), iters); err != nil {
+    return err
+  }
+  if err := tr.startSampler(len(users)); err != nil {
+    return err
+  }
+  self.users = users
+  for it := 0; it < iters; it++ {
+    dw, da, db := map[int][]float64{}, map[int]float64{}, make([]float64, rbm.m)
+    batch := make([][]Rating, tr.batchSize)
+    for n := range batch {
+      batch[n] = users[tr.drawExample(len(users))]
+      self.gradient(batch[n], dw, da, db)
+    }
+    eps := tr.learningRate() / float64(tr.batchSize)
+    for u, row := range dw {
+      for j, g := range row {
+        w := rbm.weight(u, j)
+        rbm.setWeight(u, j, w + eps * (g - tr.weightDecay * float64(tr.batchSize) * w))
+      }
+      rbm.a[u] += eps * da[u]
+    }
+    for j, g := range db {
+      rbm.b[j] += eps * g
+    }
+    tr.steps++
+    if verbose && (it + 1) % tr.logInterval() == 0 {
+      tr.logf("Training iteration: %d, rating RMSE: %.4f\n", it + 1, self.rmse(batch))
+    }
+  }
+  return nil
+}
+
+// Root mean squared error of the predictions of the users' own ratings.
+func (self *RatingsRBM) rmse(users [][]Rating) float64 {
+  sum, n := 0.0, 0
+  for _, ratings := range users {
+    for _, r := range ratings {
+      e := self.PredictRatingFor(ratings, r.Item) - float64(r.Value)
+      sum += e * e
+      n++
+    }
+  }
+  if n == 0 {
+    return 0
+  }
+  return math.Sqrt(sum / float64(n))
+}
+
+// Expected rating of item by the training user user, see PredictRatingFor.
+// Panics if there is no such user.
+func (self *RatingsRBM) PredictRating(user, item int) float64 {
+  if user < 0 || user >= len(self.users) {
+    panic(fmt.Sprintf("rbm: no training user %d of %d", user, len(self.users)))
+  }
+  return self.PredictRatingFor(self.users[user], item)
+}
+
+// Expected rating of item by a user with the given ratings, e.g. a new user:
+// the mean of RatingDistribution.
+func (self *RatingsRBM) PredictRatingFor(ratings []Rating, item int) float64 {
+  r := 0.0
+  for k, pk := range self.RatingDistribution(ratings, item) {
+    r += float64(k + 1) * pk
+  }
+  return r
+}
+
+// p(rating of item = k + 1 | ratings) for k = 0, ..., K - 1, using the
+// hidden probabilities given the ratings (one mean-field step). Panics if
+// item or a rating is out of range.
+func (self *RatingsRBM) RatingDistribution(ratings []Rating, item int) []float64 {
+  if item < 0 || item >= self.NumItems() {
+    panic(fmt.Sprintf("rbm: item %d out of range [0, %d)", item, self.NumItems()))
+  }
+  if err := self.checkRatings([][]Rating{ratings}); err != nil {
+    panic(err.Error())
+  }
+  return self.ratingProbs(item, self.hiddenMeans(ratings))
+}
@@ -1,21 +1,68 @@
 package rbm
 
 import (
-  "fmt"
   "math"
   "math/rand"
 )
 
+const noSource = "rbm: no random source, set one with WithSeed, WithSource, WithRand or WithRandomSeed"
+
 func uniform(r *rand.Rand) float64 {
   if r == nil {
-    return rand.Float64()
-  } else {
-    return r.Float64()
+    panic(noSource)
   }
+  return r.Float64()
+}
+func normal(r *rand.Rand) float64 {
+  if r == nil {
+    panic(noSource)
+  }
+  return r.NormFloat64()
+}
+func int63(r *rand.Rand) int64 {
+  if r == nil {
+    panic(noSource)
+  }
+  return r.Int63()
+}
+func perm(r *rand.Rand, n int) []int {
+  if r == nil {
+    panic(noSource)
+  }
+  return r.Perm(n)
 }
 func expit(x float64) float64 {
   return 1.0 / (1.0 + math.Exp(-x))
 }
+func toFloats(v []int) []float64 {
+  f := make([]float64, len(v))
+  for i, vi := range v {
+    f[i] = float64(vi)
+  }
+  return f
+}
+func toInts(f []float64) []int {
+  v := make([]int, len(f))
+  for i, fi := range f {
+    v[i] = int(math.Round(fi))
+  }
+  return v
+}
+func expitAll(x []float64) []float64 {
+  for k := range x {
+    x[k] = expit(x[k])
+  }
+  return x
+}
+func sign(x float64) int {
+  if x > 0 {
+    return 1
+  } else if x < 0 {
+    return -1
+  } else {
+    return 0
+  }
+}
 func bernoulli(r *rand.Rand, p float64) int {
   if uniform(r) < p {
     return 1
@@ -24,45 +71,402 @@ func bernoulli(r *rand.Rand, p float64) int {
   }
 }
 
+// Distribution of a unit given the other layer.
+type UnitType int
+
+const (
+  Binary UnitType = iota // Bernoulli with p = sigmoid(input)
+  Gaussian               // unit variance Gaussian with mean = input (visible only)
+  NReLU                  // max(0, input + N(0, sigmoid(input))) (hidden only)
+  Poisson                // count with rate exp(input) (visible only)
+  Binomial               // successes in n trials of p = sigmoid(input) (visible only)
+)
+
 type RBM struct {
   d int           // visible units
   m int           // hidden units
-  w [][]float64   // connection weights (d x m)
+  w []float64     // connection weights, flat d x m (m x d if colMajor) row-major
+  w32 []float32   // replaces w when stored as float32
+  colMajor bool
   a []float64     // visible unit biases (length d)
   b []float64     // hidden unit biases (length m)
+  visibleType UnitType
+  trials int // of Binomial visible units
+  hiddenType UnitType
+  softmaxGroups [][]int // one-of-K blocks of visible units
+  groupOf []int         // block of each visible unit, -1 if none
   cdt int         // number of contrastive divergence samples
   r *rand.Rand
+  src *Source // source of r if it is a Source
+  seed int64 // of src, if seeded is set
+  seeded bool
+  workers int // goroutines computing each batch gradient
+  dropMask []bool // kept hidden units of a dropout training copy
+  dropScale float64 // 1 / (1 - rate) of the kept units of such a copy
+  chain *chainBuffers // CD chain reused by a training copy, nil to allocate
+  version int // bumped whenever load replaces the parameters
+  history *activationHistory
+  scoreCalibration *scoreCalibration // of Score, nil for raw free energies
+}
+
+// A model with numVisible binary visible units and numHidden binary hidden
+// units, all parameters zero, configured by opts: CD-1 (WithCDK) and so on,
+// see Option. opts must give the model its random source (WithSeed,
+// WithSource, WithRand, or WithRandomSeed for a fresh seed each run); they
+// apply in order, so the source should come before WithWeightInit. Panics
+// unless both sizes are positive and there is a random source. The model
+// only computes and samples; a Trainer fits it to data.
+//
+//   m := rbm.New(784, 500, rbm.WithCDK(1), rbm.WithSeed(1),
+//     rbm.WithWeightInit(rbm.GaussianInit))
+//   t := rbm.NewTrainer(m, rbm.WithBatchSize(10))
+func New(numVisible, numHidden int, opts ...Option) *RBM {
+  return newRBM(numVisible, numHidden, 1, nil, false, opts)
+}
+
+// Same as New(numVisible, numHidden, WithCDK(cdt), WithRand(r), opts...),
+// without WithRand if r is nil.
+func NewRBM(numVisible, numHidden, cdt int, r *rand.Rand, opts ...Option) (self *RBM) {
+  return newRBM(numVisible, numHidden, cdt, r, false, opts)
+}
+
+// Same as NewRBM with WithColumnMajor.
+func NewColumnMajorRBM(numVisible, numHidden, cdt int, r *rand.Rand, opts ...Option) (self *RBM) {
+  return newRBM(numVisible, numHidden, cdt, r, true, opts)
+}
+
+func newRBM(numVisible, numHidden, cdt int, r *rand.Rand, colMajor bool, opts []Option) (self *RBM) {
+  checkModelSize(numVisible, numHidden, cdt)
+  self = blankRBM(numVisible, numHidden, cdt, colMajor)
+  self.r = r
+  self.SetOptions(opts...)
+  if self.r == nil {
+    panic(noSource)
+  }
+  return
 }
 
-func NewRBM(numVisible, numHidden, cdt int, r *rand.Rand) (self *RBM) {
-  self = new(RBM)
+// A model with all parameters zero and no random source.
+func blankRBM(numVisible, numHidden, cdt int, colMajor bool) *RBM {
+  self := new(RBM)
   self.d, self.m, self.cdt = numVisible, numHidden, cdt
   self.a = make([]float64, self.d)
   self.b = make([]float64, self.m)
-  self.w = make([][]float64, self.d)
+  self.colMajor = colMajor
+  self.w = make([]float64, self.d * self.m)
+  return self
+}
+
+// Changes the configuration of an existing model, e.g. to switch it to
+// float32 weights.
+func (self *RBM) SetOptions(opts ...Option) {
+  for _, opt := range opts {
+    opt(self)
+  }
+}
+
+// Length of a row of the weight storage: m, or d if colMajor.
+func (self *RBM) stride() int {
+  if self.colMajor {
+    return self.d
+  }
+  return self.m
+}
+
+// index into the weight storage of the weight between visible unit i and
+// hidden unit j, whatever the layout
+func (self *RBM) index(i, j int) int {
+  if self.colMajor {
+    return j * self.d + i
+  }
+  return i * self.m + j
+}
+
+// row k of the float64 weight storage, visible unit k's weights (hidden
+// unit k's if colMajor)
+func (self *RBM) row(k int) []float64 {
+  n := self.stride()
+  return self.w[k * n:(k + 1) * n]
+}
+
+// weight between visible unit i and hidden unit j, whatever the layout
+func (self *RBM) weight(i, j int) float64 {
+  if self.w32 != nil {
+    return float64(self.w32[self.index(i, j)])
+  }
+  return self.w[self.index(i, j)]
+}
+func (self *RBM) setWeight(i, j int, x float64) {
+  if self.w32 != nil {
+    self.w32[self.index(i, j)] = float32(x)
+  } else {
+    self.w[self.index(i, j)] = x
+  }
+}
+
+// Switches the weight storage to the given layout, keeping the weights and
+// the float32 mode.
+func (self *RBM) setLayout(colMajor bool) {
+  if self.colMajor == colMajor {
+    return
+  }
+  old := *self
+  if self.w32 != nil {
+    self.w32 = make([]float32, self.d * self.m)
+  } else {
+    self.w = make([]float64, self.d * self.m)
+  }
+  self.colMajor = colMajor
   for i := 0; i < self.d; i++ {
-    self.w[i] = make([]float64, self.m)
+    for j := 0; j < self.m; j++ {
+      self.setWeight(i, j, old.weight(i, j))
+    }
   }
-  self.r = r
-  return
 }
 
-func (self *RBM) GetHiddenProbability(j int, v []int) float64 {
+func (self *RBM) hiddenInput(j int, v []int) float64 {
+  checkLength("visible", len(v), self.d)
   x := self.b[j]
-  for i := 0; i < self.d; i++ {
-    x += self.w[i][j] * float64(v[i])
+  if self.colMajor && self.w32 == nil {
+    wj := self.row(j)
+    for i := 0; i < self.d; i++ {
+      x += wj[i] * float64(v[i])
+    }
+  } else {
+    for i := 0; i < self.d; i++ {
+      x += self.weight(i, j) * float64(v[i])
+    }
+  }
+  return x
+}
+
+// Probability that hidden unit j is on (h_j > 0 for NReLU units) given v.
+func (self *RBM) GetHiddenProbability(j int, v []int) float64 {
+  return self.hiddenOnProbability(self.hiddenInput(j, v))
+}
+
+// Probability that a hidden unit with input x is on.
+func (self *RBM) hiddenOnProbability(x float64) float64 {
+  if self.hiddenType == NReLU {
+    // P(x + N(0, sigmoid(x)) > 0)
+    return 0.5 * math.Erfc(-x / math.Sqrt(2 * expit(x)))
   }
   return expit(x)
 }
 func (self *RBM) GetVisibleProbability(i int, h []int) float64 {
+  checkLength("hidden", len(h), self.m)
   x := self.a[i]
-  for j := 0; j < self.m; j++ {
-    x += self.w[i][j] * float64(h[j])
+  if !self.colMajor && self.w32 == nil {
+    wi := self.row(i)
+    for j := 0; j < self.m; j++ {
+      x += wi[j] * float64(h[j])
+    }
+  } else {
+    for j := 0; j < self.m; j++ {
+      x += self.weight(i, j) * float64(h[j])
+    }
   }
   return expit(x)
 }
 
+// Pre-sigmoid activations of the whole hidden layer, b + W'v, looping in
+// storage order so both layouts read the weights contiguously.
+func (self *RBM) hiddenInputs(v []float64) []float64 {
+  return self.hiddenInputsInto(make([]float64, self.m), v)
+}
+// Same as hiddenInputs, written into x (length m).
+func (self *RBM) hiddenInputsInto(x, v []float64) []float64 {
+  checkLength("visible", len(v), self.d)
+  if self.colMajor {
+    for j := 0; j < self.m; j++ {
+      x[j] = self.dotRow(j, v, self.b[j])
+    }
+  } else {
+    copy(x, self.b)
+    for i := 0; i < self.d; i++ {
+      if v[i] != 0 {
+        self.addRow(x, i, v[i])
+      }
+    }
+  }
+  if self.dropMask != nil {
+    self.dropoutInputs(x, self.b)
+  }
+  return x
+}
+// Pre-sigmoid activations of the whole visible layer, a + Wh.
+func (self *RBM) visibleInputs(h []float64) []float64 {
+  return self.visibleInputsInto(make([]float64, self.d), h)
+}
+// Same as visibleInputs, written into x (length d).
+func (self *RBM) visibleInputsInto(x, h []float64) []float64 {
+  checkLength("hidden", len(h), self.m)
+  if self.colMajor {
+    copy(x, self.a)
+    for j := 0; j < self.m; j++ {
+      if h[j] != 0 {
+        self.addRow(x, j, h[j])
+      }
+    }
+  } else {
+    for i := 0; i < self.d; i++ {
+      x[i] = self.dotRow(i, h, self.a[i])
+    }
+  }
+  if self.dropMask != nil {
+    self.dropoutInputs(x, self.a)
+  }
+  return x
+}
+
+// E[max(0, x + N(0, sigmoid(x)))], the mean of an NReLU unit with input x
+func nreluMean(x float64) float64 {
+  s := math.Sqrt(expit(x))
+  z := x / s
+  return x * 0.5 * math.Erfc(-z / math.Sqrt2) + s * math.Exp(-z * z / 2) / math.Sqrt(2 * math.Pi)
+}
+
+// E[h | v] without recording it in the activation history
+func (self *RBM) hiddenMeans(v []float64) []float64 {
+  return self.hiddenMeansFrom(self.hiddenInputs(v), 1)
+}
+func (self *RBM) hiddenMeansFrom(x []float64, beta float64) []float64 {
+  for j := 0; j < self.m; j++ {
+    if self.dropMask != nil && !self.dropMask[j] {
+      x[j] = 0
+    } else if self.hiddenType == NReLU {
+      x[j] = nreluMean(beta * x[j])
+    } else {
+      x[j] = expit(beta * x[j])
+    }
+  }
+  return x
+}
+// Samples h given its inputs x at inverse temperature beta, overwriting x.
+func (self *RBM) sampleHiddenFrom(x []float64, beta float64) []float64 {
+  for j := 0; j < self.m; j++ {
+    if self.dropMask != nil && !self.dropMask[j] {
+      x[j] = 0
+    } else if self.hiddenType == NReLU {
+      xj := beta * x[j]
+      x[j] = math.Max(0, xj + math.Sqrt(expit(xj)) * normal(self.r))
+    } else {
+      x[j] = float64(bernoulli(self.r, expit(beta * x[j])))
+    }
+  }
+  return x
+}
+// E[v | h]
+func (self *RBM) visibleMeans(h []float64) []float64 {
+  return self.visibleMeansFrom(self.visibleInputs(h), 1)
+}
+// hiddenMeans and visibleMeans of every row of a batch
+func (self *RBM) hiddenMeansBatch(vs [][]float64) [][]float64 {
+  xs := self.hiddenInputsBatch(vs)
+  for _, x := range xs {
+    self.hiddenMeansFrom(x, 1)
+  }
+  return xs
+}
+func (self *RBM) visibleMeansBatch(hs [][]float64) [][]float64 {
+  xs := self.visibleInputsBatch(hs)
+  for _, x := range xs {
+    self.visibleMeansFrom(x, 1)
+  }
+  return xs
+}
+// E[v | h] at inverse temperature beta given the visible inputs x, which are
+// overwritten
+func (self *RBM) visibleMeansFrom(x []float64, beta float64) []float64 {
+  for i := 0; i < self.d; i++ {
+    if self.inSoftmax(i) {
+      continue
+    }
+    switch self.visibleType {
+    case Binary:
+      x[i] = expit(beta * x[i])
+    case Poisson, Binomial:
+      x[i] = self.countMean(beta * x[i])
+    }
+  }
+  for _, group := range self.softmaxGroups {
+    softmax(x, group, beta)
+  }
+  return x
+}
+
+// E[h | v], recorded in the activation history
+func (self *RBM) hiddenExpectation(v []float64) []float64 {
+  ps := self.hiddenMeans(v)
+  if self.history != nil {
+    self.recordActivation(append([]float64(nil), ps...))
+  }
+  return ps
+}
+func (self *RBM) sampleHidden(v []float64) []float64 {
+  return self.sampleHiddenInto(make([]float64, self.m), v)
+}
+func (self *RBM) sampleVisible(h []float64) []float64 {
+  return self.sampleVisibleInto(make([]float64, self.d), h)
+}
+// sampleHidden and sampleVisible writing into h (length m) and v (length d)
+func (self *RBM) sampleHiddenInto(h, v []float64) []float64 {
+  h = self.sampleHiddenFrom(self.hiddenInputsInto(h, v), 1)
+  if self.history != nil {
+    self.recordActivation(append([]float64(nil), h...))
+  }
+  return h
+}
+func (self *RBM) sampleVisibleInto(v, h []float64) []float64 {
+  return self.sampleVisibleFrom(self.visibleMeansFrom(self.visibleInputsInto(v, h), 1), 1)
+}
+// Samples v given its conditional means p at inverse temperature beta,
+// overwriting p.
+func (self *RBM) sampleVisibleFrom(p []float64, beta float64) []float64 {
+  for i := 0; i < self.d; i++ {
+    if self.inSoftmax(i) {
+      continue
+    }
+    switch self.visibleType {
+    case Gaussian:
+      p[i] += normal(self.r) / math.Sqrt(beta)
+    case Poisson, Binomial:
+      p[i] = self.sampleCount(p[i])
+    default:
+      p[i] = float64(bernoulli(self.r, p[i]))
+    }
+  }
+  for _, group := range self.softmaxGroups {
+    self.sampleSoftmax(p, group)
+  }
+  return p
+}
+// a visible vector to start a chain from when there is no data
+func (self *RBM) randomVisible() []float64 {
+  v := make([]float64, self.d)
+  for i := 0; i < self.d; i++ {
+    if self.inSoftmax(i) {
+      v[i] = 1 / float64(len(self.softmaxGroups[self.groupOf[i]]))
+    } else if self.visibleType == Gaussian {
+      v[i] = self.a[i] + normal(self.r)
+    } else if isCount(self.visibleType) {
+      v[i] = self.sampleCount(self.countMean(self.a[i]))
+    } else {
+      v[i] = float64(bernoulli(self.r, 0.5))
+    }
+  }
+  for _, group := range self.softmaxGroups {
+    self.sampleSoftmax(v, group)
+  }
+  return v
+}
+
+// A sample of hidden unit j given v, rounded to an integer for NReLU units.
 func (self *RBM) SampleHiddenUnit(j int, v []int) int {
+  if self.hiddenType == NReLU {
+    x := []float64{self.hiddenInput(j, v)}
+    return int(math.Round(self.sampleHiddenFrom(x, 1)[0]))
+  }
   p := self.GetHiddenProbability(j, v)
   return bernoulli(self.r, p)
 }
@@ -72,103 +476,179 @@ func (self *RBM) SampleVisibleUnit(i int, h []int) int {
 }
 
 func (self *RBM) SampleHiddenLayer(v []int) (h []int) {
-  h = make([]int, self.m)
-  for j := 0; j < self.m; j++ {
-    h[j] = self.SampleHiddenUnit(j, v)
-  }
-  return
+  return toInts(self.sampleHidden(toFloats(v)))
 }
+// Binary visible samples, or Gaussian ones rounded to the nearest integer;
+// see SampleVisibleLayerFloat.
 func (self *RBM) SampleVisibleLayer(h []int) (v []int) {
-  v = make([]int, self.d)
-  for i := 0; i < self.d; i++ {
-    v[i] = self.SampleVisibleUnit(i, h)
+  return toInts(self.sampleVisible(toFloats(h)))
+}
+func (self *RBM) SampleVisibleLayerFloat(h []int) []float64 {
+  return self.sampleVisible(toFloats(h))
+}
+
+// the cdt step CD chain started from v, in the model's chain buffers if it
+// has any (see chainBuffers)
+func (self *RBM) sampleChain(v []float64) (vs, hs [][]float64) {
+  buf := self.chain
+  if buf == nil || len(buf.vs) != self.cdt {
+    buf = self.newChainBuffers()
+  }
+  return self.sampleChainInto(buf, v)
+}
+func (self *RBM) sampleChainInto(buf *chainBuffers, v []float64) (vs, hs [][]float64) {
+  vs, hs = buf.vs, buf.hs
+  h1 := self.sampleHiddenInto(buf.h1, v)
+  self.sampleVisibleInto(vs[0], h1)
+  self.sampleHiddenInto(hs[0], vs[0])
+  for t := 1; t < self.cdt; t++ {
+    self.sampleVisibleInto(vs[t], hs[t - 1])
+    self.sampleHiddenInto(hs[t], vs[t])
   }
   return
 }
 
+// Storage for one CD chain, reused from example to example by a training
+// copy of the model so a batch doesn't allocate a chain per example.
+type chainBuffers struct {
+  h1 []float64
+  vs, hs [][]float64
+}
+
+func (self *RBM) newChainBuffers() *chainBuffers {
+  buf := &chainBuffers{h1: make([]float64, self.m)}
+  buf.vs, buf.hs = zeros(self.cdt, self.d), zeros(self.cdt, self.m)
+  return buf
+}
+
 func (self *RBM) SampleModel(v []int) (vs, hs [][]int) {
-  h1 := self.SampleHiddenLayer(v)
+  fvs, fhs := self.sampleChain(toFloats(v))
   vs = make([][]int, self.cdt)
   hs = make([][]int, self.cdt)
-  vs[0] = self.SampleVisibleLayer(h1)
-  hs[0] = self.SampleHiddenLayer(vs[0])
-  for t := 1; t < self.cdt; t++ {
-    vs[t] = self.SampleVisibleLayer(hs[t - 1])
-    hs[t] = self.SampleHiddenLayer(vs[t])
+  for t := 0; t < self.cdt; t++ {
+    vs[t], hs[t] = toInts(fvs[t]), toInts(fhs[t])
   }
   return
 }
 
 func (self *RBM) HiddenUnitExpectation(j int, v []int) float64 {
+  if self.hiddenType == NReLU {
+    return nreluMean(self.hiddenInput(j, v))
+  }
   return self.GetHiddenProbability(j, v)
 }
 
 func (self *RBM) HiddenLayerExpectation(v []int) []float64 {
-  ps := make([]float64, self.m)
-  for j := 0; j < self.m; j++ {
-    ps[j] = self.HiddenUnitExpectation(j, v)
-  }
-  return ps
+  return self.hiddenExpectation(toFloats(v))
+}
+func (self *RBM) HiddenLayerExpectationFloat(v []float64) []float64 {
+  return self.hiddenExpectation(v)
 }
 
-func (self *RBM) GradientStep(v []int) {
-  // TODO: allow using multipel data points at each iteration?
-  hExp := self.HiddenLayerExpectation(v)
-  vSamples, hSamples := self.SampleModel(v)
-  epsilon := 0.05
-  // visible unit bias gradient step
-  for i := 0; i < self.d; i++ {
-    vModelExp := 0.0
-    for t := 0; t < self.cdt; t++ {
-      vModelExp += float64(vSamples[t][i])
-    }
-    vModelExp /= float64(self.cdt)
-    self.a[i] += epsilon * (float64(v[i]) - vModelExp)
-  }
-  // hidden unit bias gradient step
-  for j := 0; j < self.m; j++ {
-    hModelExp := 0.0
-    for t := 0; t < self.cdt; t++ {
-      hModelExp += float64(hSamples[t][j])
+// Hidden layer expectations of every example, an N x m feature matrix. Split
+// across the goroutines set by WithWorkers; not recorded in the activation
+// history.
+func (self *RBM) Transform(vs [][]int) [][]float64 {
+  out := make([][]float64, len(vs))
+  self.parallelChunks(len(vs), func(lo, hi int) {
+    chunk := make([][]float64, hi - lo)
+    for n := range chunk {
+      chunk[n] = toFloats(vs[lo + n])
     }
-    hModelExp /= float64(self.cdt)
-    self.b[j] += epsilon * (hExp[j] - hModelExp)
+    copy(out[lo:hi], self.hiddenMeansBatch(chunk))
+  })
+  return out
+}
+// Same as Transform for real-valued visible data.
+func (self *RBM) TransformFloat(vs [][]float64) [][]float64 {
+  out := make([][]float64, len(vs))
+  self.parallelChunks(len(vs), func(lo, hi int) {
+    copy(out[lo:hi], self.hiddenMeansBatch(vs[lo:hi]))
+  })
+  return out
+}
+
+func (self *RBM) GenerateVisible(iters int) []int {
+  return self.GenerateVisibleVerbose(iters, nil)
+}
+func (self *RBM) GenerateVisibleFloat(iters int) []float64 {
+  return self.generate(iters, nil)
+}
+
+// n independent samples, each the end of its own iters step chain from a
+// random start, as n calls to GenerateVisible would give. The chains are
+// split across the goroutines set by WithWorkers; chain k draws from its own
+// stream off one draw of the model's generator, so the samples don't depend
+// on the split.
+func (self *RBM) GenerateVisibleBatch(n, iters int) [][]int {
+  vs := self.GenerateVisibleBatchFloat(n, iters)
+  out := make([][]int, n)
+  for k, v := range vs {
+    out[k] = toInts(v)
   }
-  // connection weights gradient step
-  for i := 0; i < self.d; i++ {
-    for j := 0; j < self.m; j++ {
-      dataExp := float64(v[i]) * hExp[j]
-      modelExp := 0.0
-      for t := 0; t < self.cdt; t++ {
-        modelExp += float64(vSamples[t][i]) * float64(hSamples[t][j])
-      }
-      modelExp /= float64(self.cdt)
-      self.w[i][j] += epsilon * (dataExp - modelExp)
+  return out
+}
+func (self *RBM) GenerateVisibleBatchFloat(n, iters int) [][]float64 {
+  base := uint64(int63(self.r))
+  out := make([][]float64, n)
+  self.parallelChunks(n, func(lo, hi int) {
+    for k := lo; k < hi; k++ {
+      out[k] = self.Fork(rand.New(streamSource(base, k))).generate(iters, nil)
     }
-  }
+  })
+  return out
 }
 
-func (self *RBM) Train(v [][]int, iters int, verbose bool) {
-  N := len(v)
-  for it := 0; it < iters; it++ {
-    if verbose && (it + 1) % 1000 == 0 {
-      fmt.Printf("Training iteration: %d\n", it + 1)
-    }
-    n := int(uniform(self.r) * float64(N))
-    vn := v[n]
-    self.GradientStep(vn)
+// Samples v given the hidden configuration h, then runs iters further Gibbs
+// steps from it; iters = 0 shows what h itself encodes, larger values let the
+// chain drift towards the model distribution.
+func (self *RBM) GenerateFromHidden(h []int, iters int) []int {
+  return toInts(self.GenerateFromHiddenFloat(h, iters))
+}
+func (self *RBM) GenerateFromHiddenFloat(h []int, iters int) []float64 {
+  v := self.sampleVisible(toFloats(h))
+  for t := 0; t < iters; t++ {
+    v = self.sampleVisible(self.sampleHidden(v))
   }
+  return v
 }
 
-func (self *RBM) GenerateVisible(iters int) []int {
-  v := make([]int, self.d)
-  for i := 0; i < self.d; i++ {
-    v[i] = bernoulli(self.r, 0.5)
-  }
-  var h []int
+// Reported every 10 Gibbs steps by GenerateVisibleVerbose. Gibbs updates are
+// always accepted, so Acceptance is the fraction of visible units that changed
+// state on the reported step; a value stuck near 0 means the chain is frozen.
+type GenerationProgress struct {
+  Step int
+  Energy float64
+  Acceptance float64
+}
+
+// Like GenerateVisible, but sends a GenerationProgress on progressChan every
+// 10 steps. The send blocks, so the caller must keep draining the channel. A
+// nil channel makes this identical to GenerateVisible.
+func (self *RBM) GenerateVisibleVerbose(iters int, progressChan chan<- GenerationProgress) []int {
+  return toInts(self.generate(iters, progressChan))
+}
+
+func (self *RBM) generate(iters int, progressChan chan<- GenerationProgress) []float64 {
+  v := self.randomVisible()
+  var h []float64
   for t := 0; t < iters; t++ {
-    h = self.SampleHiddenLayer(v)
-    v = self.SampleVisibleLayer(h)
+    h = self.sampleHidden(v)
+    prev := v
+    v = self.sampleVisible(h)
+    if progressChan != nil && (t + 1) % 10 == 0 {
+      changed := 0
+      for i := 0; i < self.d; i++ {
+        if v[i] != prev[i] {
+          changed++
+        }
+      }
+      progressChan <- GenerationProgress{
+        Step: t + 1,
+        Energy: self.energy(v, h),
+        Acceptance: float64(changed) / float64(self.d),
+      }
+    }
   }
   return v
 }
@@ -0,0 +1,32 @@
+package rbm
+
+import (
+  "fmt"
+  "testing"
+)
+
+// The column-major layout should make GetHiddenProbability, which walks one
+// hidden unit's weights, faster for large d.
+func BenchmarkHiddenProbability(b *testing.B) {
+  for _, d := range []int{1000, 4000} {
+    for _, colMajor := range []bool{false, true} {
+      layout := "row major"
+      if colMajor {
+        layout = "column major"
+      }
+      b.Run(fmt.Sprintf("d=%d/%s", d, layout), func(b *testing.B) {
+        m := newRBM(d, 500, 1, nil, colMajor, []Option{WithSeed(1), WithWeightInit(GaussianInit)})
+        v := make([]int, d)
+        for i := range v {
+          v[i] = i % 2
+        }
+        b.ResetTimer()
+        for n := 0; n < b.N; n++ {
+          for j := 0; j < 500; j++ {
+            m.GetHiddenProbability(j, v)
+          }
+        }
+      })
+    }
+  }
+}
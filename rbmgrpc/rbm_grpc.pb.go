@@ -0,0 +1,241 @@
+//go:build grpc
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: rbm.proto
+
+package rbmgrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RBM_Train_FullMethodName     = "/rbm.v1.RBM/Train"
+	RBM_Transform_FullMethodName = "/rbm.v1.RBM/Transform"
+	RBM_Score_FullMethodName     = "/rbm.v1.RBM/Score"
+	RBM_Sample_FullMethodName    = "/rbm.v1.RBM/Sample"
+)
+
+// RBMClient is the client API for RBM service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RBMClient interface {
+	Train(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TrainProgress], error)
+	Transform(ctx context.Context, in *TransformRequest, opts ...grpc.CallOption) (*TransformResponse, error)
+	Score(ctx context.Context, in *ScoreRequest, opts ...grpc.CallOption) (*ScoreResponse, error)
+	Sample(ctx context.Context, in *SampleRequest, opts ...grpc.CallOption) (*SampleResponse, error)
+}
+
+type rBMClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRBMClient(cc grpc.ClientConnInterface) RBMClient {
+	return &rBMClient{cc}
+}
+
+func (c *rBMClient) Train(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TrainProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RBM_ServiceDesc.Streams[0], RBM_Train_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TrainRequest, TrainProgress]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RBM_TrainClient = grpc.ServerStreamingClient[TrainProgress]
+
+func (c *rBMClient) Transform(ctx context.Context, in *TransformRequest, opts ...grpc.CallOption) (*TransformResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransformResponse)
+	err := c.cc.Invoke(ctx, RBM_Transform_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBMClient) Score(ctx context.Context, in *ScoreRequest, opts ...grpc.CallOption) (*ScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScoreResponse)
+	err := c.cc.Invoke(ctx, RBM_Score_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBMClient) Sample(ctx context.Context, in *SampleRequest, opts ...grpc.CallOption) (*SampleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SampleResponse)
+	err := c.cc.Invoke(ctx, RBM_Sample_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RBMServer is the server API for RBM service.
+// All implementations must embed UnimplementedRBMServer
+// for forward compatibility.
+type RBMServer interface {
+	Train(*TrainRequest, grpc.ServerStreamingServer[TrainProgress]) error
+	Transform(context.Context, *TransformRequest) (*TransformResponse, error)
+	Score(context.Context, *ScoreRequest) (*ScoreResponse, error)
+	Sample(context.Context, *SampleRequest) (*SampleResponse, error)
+	mustEmbedUnimplementedRBMServer()
+}
+
+// UnimplementedRBMServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRBMServer struct{}
+
+func (UnimplementedRBMServer) Train(*TrainRequest, grpc.ServerStreamingServer[TrainProgress]) error {
+	return status.Errorf(codes.Unimplemented, "method Train not implemented")
+}
+func (UnimplementedRBMServer) Transform(context.Context, *TransformRequest) (*TransformResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transform not implemented")
+}
+func (UnimplementedRBMServer) Score(context.Context, *ScoreRequest) (*ScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Score not implemented")
+}
+func (UnimplementedRBMServer) Sample(context.Context, *SampleRequest) (*SampleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sample not implemented")
+}
+func (UnimplementedRBMServer) mustEmbedUnimplementedRBMServer() {}
+func (UnimplementedRBMServer) testEmbeddedByValue()             {}
+
+// UnsafeRBMServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RBMServer will
+// result in compilation errors.
+type UnsafeRBMServer interface {
+	mustEmbedUnimplementedRBMServer()
+}
+
+func RegisterRBMServer(s grpc.ServiceRegistrar, srv RBMServer) {
+	// If the following call pancis, it indicates UnimplementedRBMServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RBM_ServiceDesc, srv)
+}
+
+func _RBM_Train_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TrainRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RBMServer).Train(m, &grpc.GenericServerStream[TrainRequest, TrainProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RBM_TrainServer = grpc.ServerStreamingServer[TrainProgress]
+
+func _RBM_Transform_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransformRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBMServer).Transform(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBM_Transform_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBMServer).Transform(ctx, req.(*TransformRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBM_Score_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBMServer).Score(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBM_Score_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBMServer).Score(ctx, req.(*ScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBM_Sample_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SampleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBMServer).Sample(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBM_Sample_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBMServer).Sample(ctx, req.(*SampleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RBM_ServiceDesc is the grpc.ServiceDesc for RBM service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RBM_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rbm.v1.RBM",
+	HandlerType: (*RBMServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Transform",
+			Handler:    _RBM_Transform_Handler,
+		},
+		{
+			MethodName: "Score",
+			Handler:    _RBM_Score_Handler,
+		},
+		{
+			MethodName: "Sample",
+			Handler:    _RBM_Sample_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Train",
+			Handler:       _RBM_Train_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rbm.proto",
+}
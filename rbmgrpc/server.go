@@ -0,0 +1,164 @@
+//go:build grpc
+
+// Package rbmgrpc serves an RBM over gRPC (see rbm.proto) for training and
+// inference from other languages. It needs google.golang.org/grpc and
+// google.golang.org/protobuf, so it only builds with -tags grpc.
+package rbmgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rbm.proto
+
+import (
+  "context"
+  "sync"
+
+  "github.com/aotimme/rbm"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
+)
+
+// Implements RBMServer around one model. Requests are serialized, since the
+// model isn't safe for concurrent use; a long Train blocks the other RPCs.
+type Server struct {
+  UnimplementedRBMServer
+  mu sync.Mutex
+  trainer *rbm.Trainer
+  model *rbm.RBM // the trainer's model
+}
+
+// Serves the model of t, which trains it. Register the result with
+// RegisterRBMServer.
+func NewServer(t *rbm.Trainer) *Server {
+  return &Server{trainer: t, model: t.Model()}
+}
+
+// Examples as float slices, checking they fit the visible layer.
+func (self *Server) examples(vs []*Vector) ([][]float64, error) {
+  d := self.model.NumVisible()
+  x := make([][]float64, len(vs))
+  for n, v := range vs {
+    if len(v.GetValues()) != d {
+      return nil, status.Errorf(codes.InvalidArgument, "example %d has %d values, want %d", n, len(v.GetValues()), d)
+    }
+    x[n] = v.GetValues()
+  }
+  return x, nil
+}
+
+func vectors(x [][]float64) []*Vector {
+  vs := make([]*Vector, len(x))
+  for n, xn := range x {
+    vs[n] = &Vector{Values: xn}
+  }
+  return vs
+}
+
+func (self *Server) Train(req *TrainRequest, stream RBM_TrainServer) error {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  x, err := self.examples(req.GetExamples())
+  if err != nil {
+    return err
+  }
+  if len(x) == 0 {
+    return status.Error(codes.InvalidArgument, "no examples")
+  }
+  monitor := x
+  if len(monitor) > 100 {
+    monitor = monitor[:100]
+  }
+  progress := &TrainProgress{}
+  send := func() error {
+    progress.ReconstructionError = self.reconstructionError(monitor)
+    return stream.Send(progress)
+  }
+  ctx := stream.Context()
+  if iters := int(req.GetIterations()); iters > 0 {
+    every := int(req.GetReportEvery())
+    if every <= 0 {
+      every = 1000
+    }
+    for done := 0; done < iters; {
+      n := every
+      if n > iters - done {
+        n = iters - done
+      }
+      if _, err := self.trainer.TrainFloatContext(ctx, x, n, false); err != nil {
+        return status.FromContextError(err).Err()
+      }
+      done += n
+      progress.Iteration = int64(done)
+      if err := send(); err != nil {
+        return err
+      }
+    }
+  } else {
+    batches := (len(x) + self.trainer.BatchSize() - 1) / self.trainer.BatchSize()
+    for epoch := 1; epoch <= int(req.GetEpochs()); epoch++ {
+      if err := ctx.Err(); err != nil {
+        return status.FromContextError(err).Err()
+      }
+      if _, err := self.trainer.TrainEpochsFloat(x, 1, false); err != nil {
+        return status.Error(codes.InvalidArgument, err.Error())
+      }
+      progress.Epoch = int32(epoch)
+      progress.Iteration += int64(batches)
+      if err := send(); err != nil {
+        return err
+      }
+    }
+  }
+  progress.Done = true
+  return send()
+}
+
+func (self *Server) reconstructionError(x [][]float64) float64 {
+  e := 0.0
+  for _, v := range x {
+    e += self.model.ReconstructionErrorFloat(v)
+  }
+  return e / float64(len(x))
+}
+
+func (self *Server) Transform(ctx context.Context, req *TransformRequest) (*TransformResponse, error) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  x, err := self.examples(req.GetExamples())
+  if err != nil {
+    return nil, err
+  }
+  return &TransformResponse{Features: vectors(self.model.TransformFloat(x))}, nil
+}
+
+func (self *Server) Score(ctx context.Context, req *ScoreRequest) (*ScoreResponse, error) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  x, err := self.examples(req.GetExamples())
+  if err != nil {
+    return nil, err
+  }
+  resp := &ScoreResponse{
+    FreeEnergy: make([]float64, len(x)),
+    ReconstructionError: make([]float64, len(x)),
+  }
+  for n, v := range x {
+    resp.FreeEnergy[n] = self.model.FreeEnergyFloat(v)
+    resp.ReconstructionError[n] = self.model.ReconstructionErrorFloat(v)
+  }
+  return resp, nil
+}
+
+func (self *Server) Sample(ctx context.Context, req *SampleRequest) (*SampleResponse, error) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+  if req.GetCount() < 0 || req.GetGibbsSteps() < 0 {
+    return nil, status.Error(codes.InvalidArgument, "negative count or gibbs_steps")
+  }
+  samples := make([][]float64, req.GetCount())
+  for k := range samples {
+    if err := ctx.Err(); err != nil {
+      return nil, status.FromContextError(err).Err()
+    }
+    samples[k] = self.model.GenerateVisibleFloat(int(req.GetGibbsSteps()))
+  }
+  return &SampleResponse{Samples: vectors(samples)}, nil
+}
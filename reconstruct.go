@@ -0,0 +1,184 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+)
+
+// Deterministic mean-field reconstruction E[v | E[h | v]] of v: visible
+// probabilities for binary units, means for Gaussian ones. Useful for
+// denoising without the noise of sampling.
+func (self *RBM) Reconstruct(v []int) []float64 {
+  return self.visibleMeans(self.hiddenMeans(toFloats(v)))
+}
+func (self *RBM) ReconstructFloat(v []float64) []float64 {
+  return self.visibleMeans(self.hiddenMeans(v))
+}
+
+// Mean-field reconstructions E[v_i | E[h | v]] (probabilities for binary
+// units) for every example in v, as an N x d matrix. Each example costs two
+// passes over the weights in storage order instead of a strided pass per unit
+// (a single matrix multiply per layer with -tags gonum).
+func (self *RBM) ReconstructBatch(v [][]int) [][]float64 {
+  vs := make([][]float64, len(v))
+  for n, vn := range v {
+    vs[n] = toFloats(vn)
+  }
+  return self.visibleMeansBatch(self.hiddenMeansBatch(vs))
+}
+
+// Error between v and its mean-field reconstruction p: cross-entropy
+// -sum_i [v_i log p_i + (1 - v_i) log(1 - p_i)] for binary visibles, squared
+// error for Gaussian and count ones.
+func (self *RBM) reconstructionError(v, p []float64) float64 {
+  e := 0.0
+  for i, pi := range p {
+    if self.visibleType != Binary {
+      diff := v[i] - pi
+      e += diff * diff
+      continue
+    }
+    pi = math.Min(math.Max(pi, 1e-12), 1 - 1e-12)
+    e -= v[i] * math.Log(pi) + (1 - v[i]) * math.Log(1 - pi)
+  }
+  return e
+}
+
+// Error of the one-step mean-field reconstruction of v, see ReconstructBatch:
+// cross-entropy for binary visible units, squared error for the others.
+func (self *RBM) ReconstructionError(v []int) float64 {
+  f := toFloats(v)
+  return self.reconstructionError(f, self.ReconstructFloat(f))
+}
+func (self *RBM) ReconstructionErrorFloat(v []float64) float64 {
+  return self.reconstructionError(v, self.ReconstructFloat(v))
+}
+
+// Mean ReconstructionError over vs.
+func (self *RBM) ReconstructionErrorBatch(vs [][]int) float64 {
+  if len(vs) == 0 {
+    return 0
+  }
+  fvs := make([][]float64, len(vs))
+  for n, v := range vs {
+    fvs[n] = toFloats(v)
+  }
+  recon := self.visibleMeansBatch(self.hiddenMeansBatch(fvs))
+  e := 0.0
+  for n, v := range fvs {
+    e += self.reconstructionError(v, recon[n])
+  }
+  return e / float64(len(vs))
+}
+
+// Indices of the budget examples of unlabeled with the highest reconstruction
+// error, worst first. Poorly reconstructed examples are the ones the model is
+// least sure about, so they are the most useful to label.
+func (self *RBM) ActiveLearnSelect(unlabeled [][]int, budget int) []int {
+  errs := make([]float64, len(unlabeled))
+  idx := make([]int, len(unlabeled))
+  for n := range unlabeled {
+    errs[n] = self.ReconstructionError(unlabeled[n])
+    idx[n] = n
+  }
+  sort.SliceStable(idx, func(x, y int) bool {
+    return errs[idx[x]] > errs[idx[y]]
+  })
+  if budget < 0 {
+    budget = 0
+  }
+  if budget < len(idx) {
+    idx = idx[:budget]
+  }
+  return idx
+}
+
+// Plain-text summary of how well the model reconstructs v, readable without
+// any plotting tools: mean Hamming error of the thresholded mean-field
+// reconstruction, the ten units with the highest error rate, an ASCII
+// histogram of per-example errors and a verdict. Only the first maxSamples
+// examples are used if maxSamples > 0.
+func (self *RBM) ReconstructionReport(v [][]int, maxSamples int) string {
+  if maxSamples > 0 && maxSamples < len(v) {
+    v = v[:maxSamples]
+  }
+  var sb strings.Builder
+  N := len(v)
+  fmt.Fprintf(&sb, "Reconstruction report: %d examples, %d visible units\n", N, self.d)
+  if N == 0 {
+    sb.WriteString("Verdict: no data\n")
+    return sb.String()
+  }
+  recon := self.ReconstructBatch(v)
+  exampleErrs := make([]int, N)
+  unitErrs := make([]int, self.d)
+  total := 0
+  for n := 0; n < N; n++ {
+    for i := 0; i < self.d; i++ {
+      r := 0
+      if recon[n][i] >= 0.5 {
+        r = 1
+      }
+      if r != v[n][i] {
+        exampleErrs[n]++
+        unitErrs[i]++
+      }
+    }
+    total += exampleErrs[n]
+  }
+  mean := float64(total) / float64(N)
+  rate := mean / float64(self.d)
+  fmt.Fprintf(&sb, "Mean Hamming error: %.2f units per example (%.2f%%)\n", mean, 100 * rate)
+
+  units := make([]int, self.d)
+  for i := range units {
+    units[i] = i
+  }
+  sort.SliceStable(units, func(x, y int) bool {
+    return unitErrs[units[x]] > unitErrs[units[y]]
+  })
+  if len(units) > 10 {
+    units = units[:10]
+  }
+  sb.WriteString("Worst reconstructed units:\n")
+  for _, i := range units {
+    fmt.Fprintf(&sb, "  unit %5d: %6.2f%% error\n", i, 100 * float64(unitErrs[i]) / float64(N))
+  }
+
+  maxErr := 0
+  for _, e := range exampleErrs {
+    if e > maxErr {
+      maxErr = e
+    }
+  }
+  numBins := 10
+  if maxErr + 1 < numBins {
+    numBins = maxErr + 1
+  }
+  binWidth := (maxErr + numBins) / numBins
+  counts := make([]int, numBins)
+  maxCount := 0
+  for _, e := range exampleErrs {
+    k := e / binWidth
+    counts[k]++
+    if counts[k] > maxCount {
+      maxCount = counts[k]
+    }
+  }
+  sb.WriteString("Hamming error distribution:\n")
+  for k, c := range counts {
+    bar := strings.Repeat("#", (40 * c + maxCount - 1) / maxCount)
+    fmt.Fprintf(&sb, "  [%4d,%4d) %-40s %d\n", k * binWidth, (k + 1) * binWidth, bar, c)
+  }
+
+  verdict := "poor"
+  if rate < 0.05 {
+    verdict = "good"
+  } else if rate < 0.15 {
+    verdict = "acceptable"
+  }
+  fmt.Fprintf(&sb, "Verdict: %s\n", verdict)
+  return sb.String()
+}
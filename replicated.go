@@ -0,0 +1,273 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+  "sort"
+)
+
+// Replicated Softmax model for bag-of-words documents (Salakhutdinov &
+// Hinton, 2009). A document of D words is D softmax units over the
+// vocabulary that share their weights, so only the word counts v matter:
+//
+//   p(h_j = 1 | v) = sigmoid(D b_j + sum_k W_kj v_k)
+//   p(word = k | h) = softmax_k(a_k + sum_j W_kj h_j)
+//
+// The hidden bias scales with the document length, which keeps long and
+// short documents on the same footing. The hidden probabilities are the
+// document's topic features, see Transform and TopWords.
+type ReplicatedSoftmax struct {
+  rbm *RBM
+  trainer *Trainer
+}
+
+// A vocabulary of numWords words and numHidden topic units; the other
+// arguments are as for NewRBM. Visible and hidden unit types are ignored.
+func NewReplicatedSoftmax(numWords, numHidden, cdt int, r *rand.Rand, opts ...Option) *ReplicatedSoftmax {
+  self := &ReplicatedSoftmax{rbm: NewRBM(numWords, numHidden, cdt, r, opts...)}
+  self.trainer = NewTrainer(self.rbm)
+  return self
+}
+
+// The underlying parameters: W, the word biases a and the per-word hidden
+// biases b.
+func (self *ReplicatedSoftmax) RBM() *RBM {
+  return self.rbm
+}
+
+// The trainer of the parameters. Its options (learning rate, batch size,
+// momentum or optimizer, schedule, weight decay) govern training.
+func (self *ReplicatedSoftmax) Trainer() *Trainer {
+  return self.trainer
+}
+
+func (self *ReplicatedSoftmax) NumWords() int {
+  return self.rbm.d
+}
+
+// Number of words in the document with counts v.
+func docLength(v []float64) float64 {
+  D := 0.0
+  for _, c := range v {
+    D += c
+  }
+  return D
+}
+
+// Hidden inputs D b + W'v of the document with counts v.
+func (self *ReplicatedSoftmax) hiddenInputs(v []float64) []float64 {
+  x := self.rbm.hiddenInputs(v)
+  D := docLength(v)
+  for j, bj := range self.rbm.b {
+    x[j] += (D - 1) * bj
+  }
+  return x
+}
+
+func (self *ReplicatedSoftmax) hiddenMeans(v []float64) []float64 {
+  return expitAll(self.hiddenInputs(v))
+}
+
+// Distribution of a word given the hidden layer.
+func (self *ReplicatedSoftmax) wordProbs(h []float64) []float64 {
+  x := self.rbm.visibleInputs(h)
+  max := math.Inf(-1)
+  for _, xk := range x {
+    max = math.Max(max, xk)
+  }
+  sum := 0.0
+  for k, xk := range x {
+    x[k] = math.Exp(xk - max)
+    sum += x[k]
+  }
+  for k := range x {
+    x[k] /= sum
+  }
+  return x
+}
+
+// Counts of D words drawn from p.
+func (self *ReplicatedSoftmax) sampleWords(p []float64, D int) []float64 {
+  cum := make([]float64, len(p))
+  sum := 0.0
+  for k, pk := range p {
+    sum += pk
+    cum[k] = sum
+  }
+  v := make([]float64, len(p))
+  for n := 0; n < D; n++ {
+    k := sort.SearchFloat64s(cum, uniform(self.rbm.r) * sum)
+    if k == len(v) {
+      k--
+    }
+    v[k]++
+  }
+  return v
+}
+
+func (self *ReplicatedSoftmax) sampleHidden(v []float64) []float64 {
+  h := self.hiddenMeans(v)
+  for j := range h {
+    h[j] = float64(bernoulli(self.rbm.r, h[j]))
+  }
+  return h
+}
+
+// CD-k statistics of the document v0, accumulated into dw, da and db.
+func (self *ReplicatedSoftmax) gradient(v0 []float64, dw [][]float64, da, db []float64) {
+  D := docLength(v0)
+  h0 := self.hiddenMeans(v0)
+  v, h := v0, h0
+  for k := 0; k < self.rbm.cdt; k++ {
+    v = self.sampleWords(self.wordProbs(self.sampleHidden(v)), int(D))
+    h = self.hiddenMeans(v)
+  }
+  for i := range v0 {
+    da[i] += v0[i] - v[i]
+    if v0[i] == 0 && v[i] == 0 {
+      continue
+    }
+    for j := range h0 {
+      dw[i][j] += v0[i] * h0[j] - v[i] * h[j]
+    }
+  }
+  for j := range h0 {
+    db[j] += D * (h0[j] - h[j])
+  }
+}
+
+// Checks that the documents are count vectors over the vocabulary.
+func (self *ReplicatedSoftmax) checkDocs(docs [][]int) error {
+  if err := self.rbm.checkInts(docs); err != nil {
+    return err
+  }
+  for n, doc := range docs {
+    for k, c := range doc {
+      if c < 0 {
+        return fmt.Errorf("rbm: document %d has count %d for word %d", n, c, k)
+      }
+    }
+  }
+  return nil
+}
+
+// Trains with CD on iters mini-batches of documents drawn at random from
+// docs, each a vector of numWords word counts. With verbose set the mean
+// per-word log-likelihood of the one-step reconstructions of the batch is
+// logged at the trainer's log interval. Returns an error, without training, if
+// there are no documents or one isn't a count vector over the vocabulary.
+func (self *ReplicatedSoftmax) Train(docs [][]int, iters int, verbose bool) error {
+  rbm, tr := self.rbm, self.trainer
+  if err := self.checkDocs(docs); err != nil {
+    return err
+  }
+  if err := checkTrainingSize(len(docs), iters); err != nil {
+    return err
+  }
+  if err := tr.startSampler(len(docs)); err != nil {
+    return err
+  }
+  for it := 0; it < iters; it++ {
+    dw := zeros(rbm.d, rbm.m)
+    da, db := make([]float64, rbm.d), make([]float64, rbm.m)
+    batch := make([][]float64, tr.batchSize)
+    for k := range batch {
+      batch[k] = toFloats(docs[tr.drawExample(len(docs))])
+      self.gradient(batch[k], dw, da, db)
+    }
+    if tr.weightDecay != 0 {
+      for i := 0; i < rbm.d; i++ {
+        for j := 0; j < rbm.m; j++ {
+          dw[i][j] -= tr.weightDecay * float64(tr.batchSize) * rbm.weight(i, j)
+        }
+      }
+    }
+    tr.applyGradient(tr.learningRate() / float64(tr.batchSize), dw, da, db)
+    tr.steps++
+    if verbose && (it + 1) % tr.logInterval() == 0 {
+      tr.logf("Training iteration: %d, reconstruction log-likelihood per word: %.4f\n", it + 1, self.reconstructionLogLikelihood(batch))
+    }
+  }
+  return nil
+}
+
+// Mean over the words of the documents of log p(word | E[h | doc]).
+func (self *ReplicatedSoftmax) reconstructionLogLikelihood(docs [][]float64) float64 {
+  ll, words := 0.0, 0.0
+  for _, v := range docs {
+    p := self.wordProbs(self.hiddenMeans(v))
+    for k, c := range v {
+      if c != 0 {
+        ll += c * math.Log(p[k])
+        words += c
+      }
+    }
+  }
+  if words == 0 {
+    return 0
+  }
+  return ll / words
+}
+
+// Topic features p(h_j = 1 | doc) of each document of word counts.
+func (self *ReplicatedSoftmax) Transform(docs [][]int) [][]float64 {
+  out := make([][]float64, len(docs))
+  for n, doc := range docs {
+    checkLength("document", len(doc), self.rbm.d)
+    out[n] = self.hiddenMeans(toFloats(doc))
+  }
+  return out
+}
+
+// The word distribution of the document's one-step mean-field
+// reconstruction, e.g. to suggest related words.
+func (self *ReplicatedSoftmax) WordDistribution(doc []int) []float64 {
+  checkLength("document", len(doc), self.rbm.d)
+  return self.wordProbs(self.hiddenMeans(toFloats(doc)))
+}
+
+// F(v) = -a.v - sum_j log(1 + exp(D b_j + W_j.v)), so that p(v) is
+// proportional to exp(-F(v)) among documents of the same length.
+func (self *ReplicatedSoftmax) FreeEnergy(doc []int) float64 {
+  checkLength("document", len(doc), self.rbm.d)
+  v := toFloats(doc)
+  f := 0.0
+  for k, c := range v {
+    f -= self.rbm.a[k] * c
+  }
+  for _, x := range self.hiddenInputs(v) {
+    f -= softplus(x)
+  }
+  return f
+}
+
+// The n words with the largest weights to hidden unit j, the words its topic
+// is about, most strongly associated first.
+func (self *ReplicatedSoftmax) TopWords(j, n int) []int {
+  words := make([]int, self.rbm.d)
+  for k := range words {
+    words[k] = k
+  }
+  sort.SliceStable(words, func(x, y int) bool {
+    return self.rbm.weight(words[x], j) > self.rbm.weight(words[y], j)
+  })
+  if n < len(words) {
+    words = words[:n]
+  }
+  return words
+}
+
+// Samples a document of D words after iters Gibbs steps from a random
+// uniform start.
+func (self *ReplicatedSoftmax) Generate(D, iters int) []int {
+  p := make([]float64, self.rbm.d)
+  for k := range p {
+    p[k] = 1 / float64(len(p))
+  }
+  v := self.sampleWords(p, D)
+  for it := 0; it < iters; it++ {
+    v = self.sampleWords(self.wordProbs(self.sampleHidden(v)), D)
+  }
+  return toInts(v)
+}
@@ -0,0 +1,197 @@
+package rbm
+
+import (
+  "bytes"
+  "encoding/gob"
+  "fmt"
+  "math"
+  "math/rand"
+  "sort"
+)
+
+// Chooses the training examples of each mini-batch. By default examples are
+// drawn uniformly with replacement; a Sampler set with WithSampler changes
+// that, e.g. to visit every example once per epoch or to give the classes of
+// an imbalanced dataset equal exposure.
+type Sampler interface {
+  // Called at the start of every training run over N examples; an error
+  // aborts the run before any step.
+  Start(N int) error
+  // The index in [0, N) of the next example, drawn with r, the model's
+  // generator.
+  Next(r *rand.Rand) int
+}
+
+// Draws the training examples of Train, TrainEpochs and their variants, and
+// of the models built on the RBM (ClassRBM, ConvRBM, ...), with s, or
+// uniformly (in shuffled epochs for TrainEpochs) if s is nil. A Sampler keeps state across draws, so trainers shouldn't share one.
+func WithSampler(s Sampler) TrainOption {
+  return func(self *Trainer) {
+    self.sampler = s
+  }
+}
+
+// Starts the sampler, if any, on a run over N examples.
+func (self *Trainer) startSampler(N int) error {
+  if self.sampler == nil {
+    return nil
+  }
+  return self.sampler.Start(N)
+}
+
+// The index of the next of N training examples.
+func (self *Trainer) drawExample(N int) int {
+  if self.sampler == nil {
+    return int(uniform(self.model.r) * float64(N))
+  }
+  return self.sampler.Next(self.model.r)
+}
+
+// Visits the examples in shuffled epochs: each epoch is a fresh random
+// permutation of all of them, so every example is used once before any is
+// used again. A run over as many examples as the last one continues its
+// epoch.
+func NewShuffledSampler() Sampler {
+  return &shuffledSampler{}
+}
+
+type shuffledSampler struct {
+  n int
+  order []int
+}
+
+func (self *shuffledSampler) Start(N int) error {
+  if N != self.n {
+    self.n, self.order = N, nil
+  }
+  return nil
+}
+
+func (self *shuffledSampler) Next(r *rand.Rand) int {
+  if len(self.order) == 0 {
+    self.order = perm(r, self.n)
+  }
+  n := self.order[0]
+  self.order = self.order[1:]
+  return n
+}
+
+// Gob form of a shuffled sampler's state: the rest of the current epoch.
+type shuffledSnapshot struct {
+  N int
+  Order []int
+}
+
+func (self *shuffledSampler) MarshalBinary() ([]byte, error) {
+  var buf bytes.Buffer
+  err := gob.NewEncoder(&buf).Encode(shuffledSnapshot{self.n, self.order})
+  return buf.Bytes(), err
+}
+
+func (self *shuffledSampler) UnmarshalBinary(data []byte) error {
+  var snap shuffledSnapshot
+  if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+    return err
+  }
+  self.n, self.order = snap.N, snap.Order
+  return nil
+}
+
+// Class-stratified sampling: draws a class uniformly, then one of its
+// examples uniformly, so every class is seen equally often whatever its size.
+// labels[n] is the class of example n; training data of another length is an
+// error.
+func NewStratifiedSampler(labels []int) Sampler {
+  classes := map[int][]int{}
+  var keys []int
+  for n, y := range labels {
+    if classes[y] == nil {
+      keys = append(keys, y)
+    }
+    classes[y] = append(classes[y], n)
+  }
+  sort.Ints(keys)
+  s := &stratifiedSampler{n: len(labels)}
+  for _, y := range keys {
+    s.classes = append(s.classes, classes[y])
+  }
+  return s
+}
+
+type stratifiedSampler struct {
+  n int
+  classes [][]int // examples of each class, in order of the labels
+}
+
+func (self *stratifiedSampler) Start(N int) error {
+  if N != self.n {
+    return fmt.Errorf("rbm: %d labels for %d examples", self.n, N)
+  }
+  return nil
+}
+
+func (self *stratifiedSampler) Next(r *rand.Rand) int {
+  class := self.classes[int(uniform(r) * float64(len(self.classes)))]
+  return class[int(uniform(r) * float64(len(class)))]
+}
+
+// Draws example n with probability proportional to weights[n], with
+// replacement. Contrast TrainWeighted, which keeps uniform draws and scales
+// the gradients instead: the expected update is the same, but this spends
+// the gradient steps on the heavy examples. Panics if a weight is negative
+// or not finite, or all are 0.
+func NewWeightedSampler(weights []float64) Sampler {
+  if err := checkWeights(weights, len(weights)); err != nil {
+    panic(err.Error())
+  }
+  cum := make([]float64, len(weights))
+  sum := 0.0
+  for n, w := range weights {
+    sum += w
+    cum[n] = sum
+  }
+  if sum == 0 || math.IsInf(sum, 0) {
+    panic("rbm: sampler weights must have a positive, finite sum")
+  }
+  return &weightedSampler{cum: cum}
+}
+
+type weightedSampler struct {
+  cum []float64 // cumulative weights
+}
+
+func (self *weightedSampler) Start(N int) error {
+  if N != len(self.cum) {
+    return fmt.Errorf("rbm: %d sampler weights for %d examples", len(self.cum), N)
+  }
+  return nil
+}
+
+func (self *weightedSampler) Next(r *rand.Rand) int {
+  total := self.cum[len(self.cum) - 1]
+  // the first example whose cumulative weight exceeds u, skipping the
+  // examples of weight 0
+  u := uniform(r) * total
+  n := sort.Search(len(self.cum), func(k int) bool { return self.cum[k] > u })
+  if n == len(self.cum) {
+    n--
+  }
+  return n
+}
+
+// A copy of s with its own draw state, for Clone. Samplers other than the
+// package's own are shared.
+func cloneSampler(s Sampler) Sampler {
+  switch s := s.(type) {
+  case *shuffledSampler:
+    return &shuffledSampler{n: s.n, order: append([]int(nil), s.order...)}
+  case *stratifiedSampler:
+    // the class lists are never modified
+    c := *s
+    return &c
+  case *weightedSampler:
+    c := *s
+    return &c
+  }
+  return s
+}
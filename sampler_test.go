@@ -0,0 +1,38 @@
+package rbm
+
+import (
+  "math/rand"
+  "testing"
+)
+
+// Always draws the same example.
+type fixedSampler struct {
+  n, draws int
+}
+
+func (self *fixedSampler) Start(N int) error {
+  return nil
+}
+
+func (self *fixedSampler) Next(r *rand.Rand) int {
+  self.draws++
+  return self.n
+}
+
+// Epoch training draws from the sampler rather than its own permutation.
+func TestEpochsUseSampler(t *testing.T) {
+  data := [][]int{{1, 0, 1, 0}, {0, 1, 0, 1}, {1, 1, 0, 0}}
+  s := &fixedSampler{n: 1}
+  tr := NewTrainer(New(4, 3, WithSeed(1)), WithSampler(s), WithBatchSize(2))
+  if _, err := tr.TrainEpochs(data, 2, false); err != nil {
+    t.Fatal(err)
+  }
+  if s.draws != 6 {
+    t.Errorf("%d draws for 2 epochs of 3 examples", s.draws)
+  }
+  // a sampler that rejects the data stops the run
+  tr.SetOptions(WithSampler(NewStratifiedSampler([]int{0, 1})))
+  if _, err := tr.TrainEpochs(data, 1, false); err == nil {
+    t.Error("mismatched sampler accepted")
+  }
+}
@@ -0,0 +1,65 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Learning rate multiplier as a function of the number of gradient steps
+// taken so far; the step size is epsilon * schedule(t).
+type Schedule func(t int) float64
+
+// Multiplies the rate by factor every `every` steps.
+func StepDecay(every int, factor float64) Schedule {
+  if every < 1 {
+    every = 1
+  }
+  return func(t int) float64 {
+    return math.Pow(factor, float64(t / every))
+  }
+}
+
+// exp(-k t).
+func ExponentialDecay(k float64) Schedule {
+  return func(t int) float64 {
+    return math.Exp(-k * float64(t))
+  }
+}
+
+// 1 / (1 + k t).
+func InverseTimeDecay(k float64) Schedule {
+  return func(t int) float64 {
+    return 1 / (1 + k * float64(t))
+  }
+}
+
+// Ramps the rate up linearly over the first steps, then follows then (a
+// constant rate if nil) counting from the end of the warmup.
+func LinearWarmup(steps int, then Schedule) Schedule {
+  return func(t int) float64 {
+    if t < steps {
+      return float64(t + 1) / float64(steps)
+    }
+    if then == nil {
+      return 1
+    }
+    return then(t - steps)
+  }
+}
+
+// Varies the learning rate with the number of gradient steps taken (default:
+// constant). The step count carries over between training runs; passing the
+// option again restarts it.
+func WithSchedule(s Schedule) TrainOption {
+  return func(self *Trainer) {
+    self.schedule = s
+    self.steps = 0
+  }
+}
+
+// Step size of the next gradient step.
+func (self *Trainer) learningRate() float64 {
+  if self.schedule == nil {
+    return self.epsilon
+  }
+  return self.epsilon * self.schedule(self.steps)
+}
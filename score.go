@@ -0,0 +1,73 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Mean and standard deviation of the free energies of a reference set.
+type scoreCalibration struct {
+  mean, std float64
+}
+
+// Anomaly scores of the examples: their free energies, which are high for
+// inputs the model finds improbable, so novel or anomalous inputs stand out
+// against the training data. After CalibrateScores they are standardized
+// against the reference set, so a score of 3 is three standard deviations
+// above the reference free energies; a threshold is then independent of the
+// model size.
+func (self *RBM) Score(vs [][]int) []float64 {
+  scores := make([]float64, len(vs))
+  for n, v := range vs {
+    scores[n] = self.score(toFloats(v))
+  }
+  return scores
+}
+// Same as Score for real-valued data (Gaussian visibles).
+func (self *RBM) ScoreFloat(vs [][]float64) []float64 {
+  scores := make([]float64, len(vs))
+  for n, v := range vs {
+    scores[n] = self.score(v)
+  }
+  return scores
+}
+
+func (self *RBM) score(v []float64) float64 {
+  f := self.freeEnergy(v)
+  if c := self.scoreCalibration; c != nil {
+    return (f - c.mean) / c.std
+  }
+  return f
+}
+
+// Makes Score standardize free energies by the mean and standard deviation
+// of those of reference, which is typically (a sample of) the training data.
+// The calibration refers to the current parameters, so call it again after
+// further training; loading new parameters removes it, as does an empty
+// reference.
+func (self *RBM) CalibrateScores(reference [][]int) {
+  f := make([][]float64, len(reference))
+  for n, v := range reference {
+    f[n] = toFloats(v)
+  }
+  self.CalibrateScoresFloat(f)
+}
+// Same as CalibrateScores for real-valued data.
+func (self *RBM) CalibrateScoresFloat(reference [][]float64) {
+  self.scoreCalibration = nil
+  if len(reference) == 0 {
+    return
+  }
+  mean, sq := 0.0, 0.0
+  for _, v := range reference {
+    f := self.freeEnergy(v)
+    mean += f
+    sq += f * f
+  }
+  N := float64(len(reference))
+  mean /= N
+  std := math.Sqrt(math.Max(sq / N - mean * mean, 0))
+  if std == 0 {
+    std = 1
+  }
+  self.scoreCalibration = &scoreCalibration{mean, std}
+}
@@ -0,0 +1,135 @@
+package rbm
+
+import (
+  "encoding/json"
+  "fmt"
+)
+
+// Portable description of a model, used by the parameter server and for JSON
+// serialization. W is d x m regardless of the weight layout.
+type Params struct {
+  NumVisible int `json:"num_visible"`
+  NumHidden int `json:"num_hidden"`
+  CDT int `json:"cdt"`
+  ColMajor bool `json:"col_major,omitempty"`
+  VisibleUnits UnitType `json:"visible_units"`
+  HiddenUnits UnitType `json:"hidden_units"`
+  Trials int `json:"trials,omitempty"` // of Binomial visible units
+  SoftmaxGroups [][]int `json:"softmax_groups,omitempty"`
+  W [][]float64 `json:"w"`
+  A []float64 `json:"a"`
+  B []float64 `json:"b"`
+}
+
+func (self *RBM) params() *Params {
+  p := &Params{NumVisible: self.d, NumHidden: self.m, CDT: self.cdt, ColMajor: self.colMajor}
+  p.VisibleUnits, p.HiddenUnits = self.visibleType, self.hiddenType
+  if self.visibleType == Binomial {
+    p.Trials = self.binomialTrials()
+  }
+  p.SoftmaxGroups = self.softmaxGroups
+  p.W = make([][]float64, self.d)
+  for i := 0; i < self.d; i++ {
+    p.W[i] = make([]float64, self.m)
+    for j := 0; j < self.m; j++ {
+      p.W[i][j] = self.weight(i, j)
+    }
+  }
+  p.A = append([]float64(nil), self.a...)
+  p.B = append([]float64(nil), self.b...)
+  return p
+}
+
+func (p *Params) validate() error {
+  if p.NumVisible <= 0 || p.NumHidden <= 0 {
+    return fmt.Errorf("rbm: invalid dimensions %d x %d", p.NumVisible, p.NumHidden)
+  }
+  if p.CDT < 1 {
+    return fmt.Errorf("rbm: cdt must be at least 1, got %d", p.CDT)
+  }
+  if len(p.A) != p.NumVisible || len(p.B) != p.NumHidden || len(p.W) != p.NumVisible {
+    return fmt.Errorf("rbm: parameters do not match %d x %d model", p.NumVisible, p.NumHidden)
+  }
+  for i, wi := range p.W {
+    if len(wi) != p.NumHidden {
+      return fmt.Errorf("rbm: weight row %d has length %d, want %d", i, len(wi), p.NumHidden)
+    }
+  }
+  switch p.VisibleUnits {
+  case Binary, Gaussian, Poisson, Binomial:
+  default:
+    return fmt.Errorf("rbm: unknown visible unit type %d", p.VisibleUnits)
+  }
+  if p.HiddenUnits != Binary && p.HiddenUnits != NReLU {
+    return fmt.Errorf("rbm: unknown hidden unit type %d", p.HiddenUnits)
+  }
+  if p.VisibleUnits == Binomial && p.Trials < 1 {
+    return fmt.Errorf("rbm: binomial visible units need at least 1 trial, got %d", p.Trials)
+  }
+  grouped := make([]bool, p.NumVisible)
+  for _, group := range p.SoftmaxGroups {
+    for _, i := range group {
+      if i < 0 || i >= p.NumVisible {
+        return fmt.Errorf("rbm: softmax group unit %d out of range", i)
+      }
+      if grouped[i] {
+        return fmt.Errorf("rbm: visible unit %d in more than one softmax group", i)
+      }
+      grouped[i] = true
+    }
+  }
+  return nil
+}
+
+// A model holding p, without a random source.
+func (p *Params) model() *RBM {
+  self := blankRBM(p.NumVisible, p.NumHidden, p.CDT, p.ColMajor)
+  self.SetOptions(WithVisibleUnits(p.VisibleUnits), WithHiddenUnits(p.HiddenUnits),
+    WithBinomialTrials(p.Trials), WithSoftmaxGroups(p.SoftmaxGroups))
+  copy(self.a, p.A)
+  copy(self.b, p.B)
+  for i := 0; i < self.d; i++ {
+    for j := 0; j < self.m; j++ {
+      self.setWeight(i, j, p.W[i][j])
+    }
+  }
+  return self
+}
+
+// Replaces the parameters and structure of self by those of p, keeping its
+// random source and its other options. The zero RBM is left without a
+// source, to be set with SetOptions before it samples.
+func (self *RBM) load(p *Params) error {
+  if err := p.validate(); err != nil {
+    return err
+  }
+  m := p.model()
+  self.d, self.m, self.cdt = m.d, m.m, m.cdt
+  single := self.w32 != nil
+  self.w, self.w32, self.colMajor, self.a, self.b = m.w, nil, m.colMajor, m.a, m.b
+  if single {
+    WithFloat32()(self)
+  }
+  self.visibleType, self.hiddenType, self.trials = m.visibleType, m.hiddenType, m.trials
+  self.softmaxGroups, self.groupOf = m.softmaxGroups, m.groupOf
+  // trainers drop their state tied to the old parameters
+  self.version++
+  self.scoreCalibration = nil
+  return nil
+}
+
+// Encodes the model structure and parameters (not the Trainer's
+// configuration) as JSON.
+func (self *RBM) MarshalJSON() ([]byte, error) {
+  return json.Marshal(self.params())
+}
+
+// Decodes a model written by MarshalJSON into self. The zero RBM is a valid
+// target: var m rbm.RBM; json.Unmarshal(data, &m).
+func (self *RBM) UnmarshalJSON(data []byte) error {
+  var p Params
+  if err := json.Unmarshal(data, &p); err != nil {
+    return err
+  }
+  return self.load(&p)
+}
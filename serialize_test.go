@@ -0,0 +1,63 @@
+package rbm
+
+import (
+  "bytes"
+  "encoding/json"
+  "testing"
+)
+
+// Models covering the parts of the structure the formats have to carry.
+func roundTripModels() map[string]*RBM {
+  return map[string]*RBM{
+    "binary": testModel(6, 4, 1),
+    "column major": New(6, 4, WithColumnMajor(), WithSeed(2), WithWeightInit(GaussianInit)),
+    "float32": New(6, 4, WithFloat32(), WithSeed(3), WithWeightInit(GaussianInit)),
+    "softmax": New(6, 4, WithSoftmaxGroups([][]int{{0, 1, 2}, {4, 5}}), WithSeed(4), WithWeightInit(GaussianInit)),
+    "binomial": New(6, 4, WithVisibleUnits(Binomial), WithBinomialTrials(5), WithSeed(5), WithWeightInit(GaussianInit)),
+    "nrelu": New(6, 4, WithVisibleUnits(Gaussian), WithHiddenUnits(NReLU), WithCDK(3), WithSeed(6), WithWeightInit(GaussianInit)),
+  }
+}
+
+func sameModel(t *testing.T, name string, got, want *RBM) {
+  t.Helper()
+  g, w := got.params(), want.params()
+  gj, _ := json.Marshal(g)
+  wj, _ := json.Marshal(w)
+  if !bytes.Equal(gj, wj) {
+    t.Errorf("%s: decoded %s, want %s", name, gj, wj)
+  }
+  if got.colMajor != want.colMajor {
+    t.Errorf("%s: decoded column major %v, want %v", name, got.colMajor, want.colMajor)
+  }
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+  for name, m := range roundTripModels() {
+    data, err := json.Marshal(m)
+    if err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    var got RBM
+    if err := json.Unmarshal(data, &got); err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    sameModel(t, name, &got, m)
+  }
+}
+
+// Decoding malformed parameters is an error, not a panic.
+func TestDecodeInvalid(t *testing.T) {
+  for name, js := range map[string]string{
+    "shared softmax unit": `{"num_visible":2,"num_hidden":1,"cdt":1,"softmax_groups":[[0,1],[1]],"w":[[0],[0]],"a":[0,0],"b":[0]}`,
+    "softmax unit range": `{"num_visible":2,"num_hidden":1,"cdt":1,"softmax_groups":[[0,2]],"w":[[0],[0]],"a":[0,0],"b":[0]}`,
+    "visible type": `{"num_visible":2,"num_hidden":1,"cdt":1,"visible_units":9,"w":[[0],[0]],"a":[0,0],"b":[0]}`,
+    "hidden type": `{"num_visible":2,"num_hidden":1,"cdt":1,"hidden_units":1,"w":[[0],[0]],"a":[0,0],"b":[0]}`,
+    "weights": `{"num_visible":2,"num_hidden":1,"cdt":1,"w":[[0]],"a":[0,0],"b":[0]}`,
+    "cdt": `{"num_visible":2,"num_hidden":1,"cdt":0,"w":[[0],[0]],"a":[0,0],"b":[0]}`,
+  } {
+    var m RBM
+    if err := json.Unmarshal([]byte(js), &m); err == nil {
+      t.Errorf("%s: decoded without error", name)
+    }
+  }
+}
@@ -0,0 +1,72 @@
+package rbm
+
+import (
+  "context"
+  "errors"
+  "fmt"
+)
+
+// Soft binary data: each visible value is the probability in [0, 1] that the
+// unit is on, e.g. a grayscale pixel scaled to [0, 1], and is used as is, as
+// the data expectation of the unit in the positive phase of the gradient,
+// instead of binarizing it first and losing the information in between. The
+// negative phase samples binary units as usual.
+
+// Same as Train for binary visible units with soft data. Returns an error,
+// before taking any step, if an example doesn't have one value per visible
+// unit or has a value outside [0, 1]. The pseudo-likelihood the progress
+// lines report is only indicative here, as it treats the values as binary.
+func (self *Trainer) TrainSoft(v [][]float64, iters int, verbose bool) (History, error) {
+  return self.TrainSoftContext(context.Background(), v, iters, verbose)
+}
+// Same as TrainSoft, stopping once ctx is done, see TrainContext.
+func (self *Trainer) TrainSoftContext(ctx context.Context, v [][]float64, iters int, verbose bool) (History, error) {
+  if err := self.model.checkSoft(v); err != nil {
+    return History{}, err
+  }
+  return self.train(ctx, len(v), func(n int) []float64 { return v[n] }, iters, verbose)
+}
+
+// Same as Transform for soft data; panics if a value is outside [0, 1].
+func (self *RBM) TransformSoft(v [][]float64) [][]float64 {
+  if err := self.checkSoft(v); err != nil {
+    panic(err.Error())
+  }
+  return self.TransformFloat(v)
+}
+
+// Same as HiddenLayerExpectation for a soft v.
+func (self *RBM) HiddenLayerExpectationSoft(v []float64) []float64 {
+  checkLength("visible", len(v), self.d)
+  if err := checkProbabilities(0, v); err != nil {
+    panic(err.Error())
+  }
+  return self.hiddenExpectation(v)
+}
+
+// Checks soft examples: one value in [0, 1] per visible unit, for binary
+// visible units.
+func (self *RBM) checkSoft(v [][]float64) error {
+  if self.visibleType != Binary {
+    return errors.New("rbm: soft data needs binary visible units")
+  }
+  if err := self.checkFloats(v); err != nil {
+    return err
+  }
+  for n, vn := range v {
+    if err := checkProbabilities(n, vn); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func checkProbabilities(n int, v []float64) error {
+  for i, x := range v {
+    // also rejects NaN
+    if !(x >= 0 && x <= 1) {
+      return fmt.Errorf("rbm: example %d has value %g at unit %d, want a probability in [0, 1]", n, x, i)
+    }
+  }
+  return nil
+}
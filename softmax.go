@@ -0,0 +1,66 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Groups visible units into softmax (one-of-K) blocks, e.g. the K indicator
+// units of a categorical feature. Exactly one unit of each block is on in a
+// sample and the block's conditional is a softmax over its inputs, so the
+// data should also have exactly one unit per block set. Units outside every
+// block keep the visible unit type. Panics if a unit appears in two blocks.
+func WithSoftmaxGroups(groups [][]int) Option {
+  return func(self *RBM) {
+    self.softmaxGroups = nil
+    self.groupOf = make([]int, self.d)
+    for i := range self.groupOf {
+      self.groupOf[i] = -1
+    }
+    for g, group := range groups {
+      for _, i := range group {
+        if self.groupOf[i] >= 0 {
+          panic("rbm: visible unit in more than one softmax group")
+        }
+        self.groupOf[i] = g
+      }
+      self.softmaxGroups = append(self.softmaxGroups, append([]int(nil), group...))
+    }
+  }
+}
+
+func (self *RBM) inSoftmax(i int) bool {
+  return self.groupOf != nil && self.groupOf[i] >= 0
+}
+
+// replaces x[group] by softmax(beta * x[group])
+func softmax(x []float64, group []int, beta float64) {
+  max := math.Inf(-1)
+  for _, i := range group {
+    max = math.Max(max, beta * x[i])
+  }
+  sum := 0.0
+  for _, i := range group {
+    x[i] = math.Exp(beta * x[i] - max)
+    sum += x[i]
+  }
+  for _, i := range group {
+    x[i] /= sum
+  }
+}
+
+// replaces the probabilities p[group] by a one-hot sample from them
+func (self *RBM) sampleSoftmax(p []float64, group []int) {
+  u := uniform(self.r)
+  chosen := group[len(group) - 1]
+  for _, i := range group {
+    u -= p[i]
+    if u < 0 {
+      chosen = i
+      break
+    }
+  }
+  for _, i := range group {
+    p[i] = 0
+  }
+  p[chosen] = 1
+}
@@ -0,0 +1,121 @@
+package rbm
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "math/rand"
+)
+
+// A rand.Source64 (SplitMix64) whose state can be saved and restored, unlike
+// the math/rand sources. Models seeded through WithSeed use one, which lets
+// SaveState capture the random stream along with everything else.
+type Source struct {
+  state uint64
+}
+
+func NewSource(seed int64) *Source {
+  return &Source{uint64(seed)}
+}
+
+func (self *Source) Seed(seed int64) {
+  self.state = uint64(seed)
+}
+
+func (self *Source) Uint64() uint64 {
+  self.state += 0x9e3779b97f4a7c15
+  z := self.state
+  z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+  z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+  return z ^ (z >> 31)
+}
+
+func (self *Source) Int63() int64 {
+  return int64(self.Uint64() >> 1)
+}
+
+func (self *Source) MarshalBinary() ([]byte, error) {
+  return binary.LittleEndian.AppendUint64(nil, self.state), nil
+}
+
+func (self *Source) UnmarshalBinary(data []byte) error {
+  if len(data) != 8 {
+    return errors.New("rbm: bad Source state")
+  }
+  self.state = binary.LittleEndian.Uint64(data)
+  return nil
+}
+
+// Source of stream n of the family base: its seed is a SplitMix64 hash of
+// (base, n), so distinct streams start at unrelated points of the sequence.
+func streamSource(base uint64, n int) *Source {
+  h := Source{base + uint64(n) * 0xd1b54a32d192ed03}
+  return &Source{h.Uint64()}
+}
+
+// Seeds the model's random source with a Source, making training it
+// resumable bit for bit through a Trainer's SaveState and LoadState.
+func WithSeed(seed int64) Option {
+  return func(self *RBM) {
+    self.src = NewSource(seed)
+    self.r = rand.New(self.src)
+    self.seed, self.seeded = seed, true
+  }
+}
+
+// Samples and trains with src, any rand.Source (or rand.Source64, whose
+// Uint64 is then used directly): a PCG or xoshiro generator, a crypto-backed
+// one, or a quasi-random sequence through Float64Adapter. Only a *Source
+// has its state captured by SaveState. Panics if src is nil.
+func WithSource(src rand.Source) Option {
+  if src == nil {
+    panic("rbm: nil random source, use WithSeed for a seeded one")
+  }
+  return func(self *RBM) {
+    self.r = rand.New(src)
+    self.src, _ = src.(*Source)
+    self.seed, self.seeded = 0, false
+  }
+}
+
+// The minimal random source: uniform values in [0, 1), e.g. the points of a
+// low-discrepancy sequence.
+type Float64Source interface {
+  Float64() float64
+}
+
+// src as a rand.Source for WithSource. Int63 is the value scaled to
+// [0, 2^63), so the model's uniform draws reproduce the values of src to
+// within 2^-63; Seed is passed on if src has a Seed(int64) method and
+// ignored otherwise.
+func Float64Adapter(src Float64Source) rand.Source {
+  return float64Source{src}
+}
+
+type float64Source struct {
+  src Float64Source
+}
+
+func (self float64Source) Int63() int64 {
+  f := self.src.Float64()
+  if !(f >= 0 && f < 1) {
+    panic(fmt.Sprintf("rbm: Float64Source value %g outside [0, 1)", f))
+  }
+  return int64(f * (1 << 63))
+}
+
+func (self float64Source) Seed(seed int64) {
+  if s, ok := self.src.(interface{ Seed(int64) }); ok {
+    s.Seed(seed)
+  }
+}
+
+// Seeds the model's Source with a seed drawn from the math/rand globals,
+// which are randomly seeded at start-up: the explicit choice of a different
+// stream on every run. Seed reports the seed drawn, so that a run can still
+// be repeated with WithSeed.
+func WithRandomSeed() Option {
+  return func(self *RBM) {
+    WithSeed(rand.Int63())(self)
+  }
+}
@@ -0,0 +1,68 @@
+package rbm
+
+import (
+  "encoding/json"
+  "math/rand"
+  "testing"
+)
+
+func mustPanic(t *testing.T, name string, f func()) {
+  t.Helper()
+  defer func() {
+    if recover() == nil {
+      t.Errorf("%s: no panic", name)
+    }
+  }()
+  f()
+}
+
+// Nothing falls back to an unseeded source: it is either given or asked for.
+func TestExplicitSource(t *testing.T) {
+  mustPanic(t, "New", func() { New(3, 2) })
+  mustPanic(t, "NewRBM", func() { NewRBM(3, 2, 1, nil) })
+  mustPanic(t, "init before source", func() { New(3, 2, WithWeightInit(GaussianInit), WithSeed(1)) })
+  mustPanic(t, "Fork", func() { New(3, 2, WithSeed(1)).Fork(nil) })
+  mustPanic(t, "NewWorker", func() { NewWorker(nil, [][]int{{1, 0, 1}}, 1, nil) })
+  data, err := json.Marshal(New(3, 2, WithSeed(1)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  var m RBM
+  if err := json.Unmarshal(data, &m); err != nil {
+    t.Fatal(err)
+  }
+  mustPanic(t, "decoded", func() { m.GenerateVisible(1) })
+  m.SetOptions(WithRandomSeed())
+  m.GenerateVisible(1)
+  if _, ok := m.Seed(); !ok {
+    t.Error("WithRandomSeed: no seed reported")
+  }
+  New(3, 2, WithSource(rand.NewSource(1))).GenerateVisible(1)
+}
+
+func trainSeeded(t *testing.T, opt Option) *RBM {
+  t.Helper()
+  data := [][]int{{1, 0, 1, 0}, {0, 1, 0, 1}, {1, 1, 0, 0}}
+  tr := NewTrainer(New(4, 3, opt, WithWeightInit(GaussianInit)), WithBatchSize(2))
+  if _, err := tr.Train(data, 10, false); err != nil {
+    t.Fatal(err)
+  }
+  return tr.Model()
+}
+
+// A seed fixes the whole run, including one drawn by WithRandomSeed.
+func TestSeedReproducible(t *testing.T) {
+  want := trainSeeded(t, WithSeed(5))
+  sameModel(t, "same seed", trainSeeded(t, WithSeed(5)), want)
+  sameModel(t, "Source", trainSeeded(t, WithSource(NewSource(5))), want)
+  random := trainSeeded(t, WithRandomSeed())
+  seed, ok := random.Seed()
+  if !ok {
+    t.Fatal("WithRandomSeed: no seed reported")
+  }
+  sameModel(t, "reported seed", trainSeeded(t, WithSeed(seed)), random)
+  other := trainSeeded(t, WithSeed(6))
+  if other.weight(0, 0) == want.weight(0, 0) {
+    t.Error("seeds 5 and 6 gave the same weights")
+  }
+}
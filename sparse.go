@@ -0,0 +1,76 @@
+package rbm
+
+import (
+  "context"
+)
+
+// Sparse binary visible vectors are given as the sorted indices of their
+// active units, e.g. the words present in a bag-of-words document. The
+// hidden layer computations below then cost O(nnz m) instead of O(d m).
+
+// Dense 0/1 vector of length d with the given units on.
+func (self *RBM) dense(active []int) []float64 {
+  v := make([]float64, self.d)
+  for _, i := range active {
+    v[i] = 1
+  }
+  return v
+}
+
+// b + W'v for the sparse binary v
+func (self *RBM) hiddenInputsSparse(active []int) []float64 {
+  x := make([]float64, self.m)
+  copy(x, self.b)
+  if self.colMajor {
+    for j := range x {
+      for _, i := range active {
+        x[j] += self.weight(i, j)
+      }
+    }
+  } else {
+    for _, i := range active {
+      self.addRow(x, i, 1)
+    }
+  }
+  return x
+}
+
+// Same as HiddenLayerExpectation for a sparse binary v.
+func (self *RBM) HiddenLayerExpectationSparse(active []int) []float64 {
+  ps := self.hiddenMeansFrom(self.hiddenInputsSparse(active), 1)
+  if self.history != nil {
+    self.recordActivation(append([]float64(nil), ps...))
+  }
+  return ps
+}
+
+// Same as SampleHiddenLayer for a sparse binary v.
+func (self *RBM) SampleHiddenLayerSparse(active []int) []int {
+  h := self.sampleHiddenFrom(self.hiddenInputsSparse(active), 1)
+  if self.history != nil {
+    self.recordActivation(append([]float64(nil), h...))
+  }
+  return toInts(h)
+}
+
+// Same as FreeEnergy for a sparse binary v.
+func (self *RBM) FreeEnergySparse(active []int) float64 {
+  f := 0.0
+  for _, i := range active {
+    f -= self.a[i]
+  }
+  for _, x := range self.hiddenInputsSparse(active) {
+    f -= softplus(x)
+  }
+  return f
+}
+
+// Same as Train for sparse binary examples. Each example is expanded to a
+// dense vector only while it is used, so the dataset stays in sparse form;
+// the negative phase of CD is dense, so a gradient step still costs O(d m).
+func (self *Trainer) TrainSparse(v [][]int, iters int, verbose bool) (History, error) {
+  if err := self.model.checkSparse(v); err != nil {
+    return History{}, err
+  }
+  return self.train(context.Background(), len(v), func(n int) []float64 { return self.model.dense(v[n]) }, iters, verbose)
+}
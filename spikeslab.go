@@ -0,0 +1,253 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+)
+
+// Spike-and-slab RBM for real-valued data (Courville, Bergstra & Bengio,
+// 2011). Every hidden unit pairs a binary spike h_j with a real slab s_j:
+//
+//   E(v, s, h) = v'Λv / 2 - a.v - sum_j (h_j s_j W_j.v - α_j s_j^2 / 2 + b_j h_j)
+//
+// with diagonal visible precision Λ and slab precisions α. Summing out the
+// slabs gives
+//
+//   p(h_j = 1 | v) = sigmoid(b_j + (W_j.v)^2 / (2 α_j))
+//   p(s_j | v, h_j) = N(h_j W_j.v / α_j, 1 / α_j)
+//   p(v | s, h) = N(Λ^-1 (a + sum_j W_j s_j h_j), Λ^-1)
+//
+// so a spike responds to the magnitude of its filter's response rather than
+// its sign and the slabs carry the intensity, which lets the model capture
+// the covariance of the data where a Gaussian-Bernoulli RBM only shifts its
+// mean. Train it on standardized data. The model is only normalizable while
+// Λ - sum_j h_j W_j W_j' / α_j stays positive definite: short CD chains don't
+// depend on that, but long Gibbs runs (Generate) can diverge if the filters
+// grow large relative to the precisions, which weight decay keeps in check.
+type SpikeSlabRBM struct {
+  rbm *RBM
+  trainer *Trainer
+  alpha []float64  // slab precisions (m)
+  lambda []float64 // visible precisions (d)
+}
+
+// The arguments are as for NewRBM; the unit type options are ignored. The
+// slab and visible precisions start at 1.
+func NewSpikeSlabRBM(numVisible, numHidden, cdt int, r *rand.Rand, opts ...Option) *SpikeSlabRBM {
+  self := &SpikeSlabRBM{rbm: NewRBM(numVisible, numHidden, cdt, r, opts...)}
+  self.trainer = NewTrainer(self.rbm)
+  self.alpha = make([]float64, numHidden)
+  for j := range self.alpha {
+    self.alpha[j] = 1
+  }
+  self.lambda = make([]float64, numVisible)
+  for i := range self.lambda {
+    self.lambda[i] = 1
+  }
+  return self
+}
+
+// The underlying parameters: the filters W, the visible biases a and the
+// spike biases b.
+func (self *SpikeSlabRBM) RBM() *RBM {
+  return self.rbm
+}
+
+// The trainer of the parameters. Its options (learning rate, batch size,
+// momentum or optimizer, schedule, weight decay) govern training.
+func (self *SpikeSlabRBM) Trainer() *Trainer {
+  return self.trainer
+}
+
+// Sets the slab precision of every hidden unit. Larger precisions keep the
+// slabs small, making for a smoother but less expressive model. Panics unless
+// alpha is positive.
+func (self *SpikeSlabRBM) SetSlabPrecision(alpha float64) {
+  if !(alpha > 0) {
+    panic(fmt.Sprintf("rbm: slab precision must be positive, got %v", alpha))
+  }
+  for j := range self.alpha {
+    self.alpha[j] = alpha
+  }
+}
+
+// Sets the precision of every visible unit, 1 for standardized data. Panics
+// unless lambda is positive.
+func (self *SpikeSlabRBM) SetVisiblePrecision(lambda float64) {
+  if !(lambda > 0) {
+    panic(fmt.Sprintf("rbm: visible precision must be positive, got %v", lambda))
+  }
+  for i := range self.lambda {
+    self.lambda[i] = lambda
+  }
+}
+
+// Filter responses W_j.v and spike probabilities p(h_j = 1 | v).
+func (self *SpikeSlabRBM) spikes(v []float64) (u, p []float64) {
+  u = self.rbm.hiddenInputs(v)
+  p = make([]float64, len(u))
+  for j, bj := range self.rbm.b {
+    u[j] -= bj
+    p[j] = expit(bj + u[j] * u[j] / (2 * self.alpha[j]))
+  }
+  return
+}
+
+// A sample of the spikes and, for the active ones, their slabs given v; the
+// result is the products h_j s_j, all the visible layer sees.
+func (self *SpikeSlabRBM) sampleHidden(v []float64) []float64 {
+  u, p := self.spikes(v)
+  hs := make([]float64, len(u))
+  for j := range hs {
+    if bernoulli(self.rbm.r, p[j]) == 1 {
+      hs[j] = u[j] / self.alpha[j] + normal(self.rbm.r) / math.Sqrt(self.alpha[j])
+    }
+  }
+  return hs
+}
+
+// E[v | s, h] given the products hs = h * s.
+func (self *SpikeSlabRBM) visibleMeans(hs []float64) []float64 {
+  x := self.rbm.visibleInputs(hs)
+  for i := range x {
+    x[i] /= self.lambda[i]
+  }
+  return x
+}
+
+func (self *SpikeSlabRBM) sampleVisible(hs []float64) []float64 {
+  x := self.visibleMeans(hs)
+  for i := range x {
+    x[i] += normal(self.rbm.r) / math.Sqrt(self.lambda[i])
+  }
+  return x
+}
+
+// E[h_j s_j | v] = p(h_j = 1 | v) W_j.v / α_j
+func (self *SpikeSlabRBM) slabMeans(v []float64) []float64 {
+  u, p := self.spikes(v)
+  for j := range u {
+    u[j] *= p[j] / self.alpha[j]
+  }
+  return u
+}
+
+// Accumulates the gradient of -F at v, scaled by sign, into dw, da and db.
+func (self *SpikeSlabRBM) addFreeEnergyGradient(v []float64, sign float64, dw [][]float64, da, db []float64) {
+  u, p := self.spikes(v)
+  for i, vi := range v {
+    da[i] += sign * vi
+    if vi == 0 {
+      continue
+    }
+    for j := range u {
+      dw[i][j] += sign * p[j] * u[j] / self.alpha[j] * vi
+    }
+  }
+  for j := range p {
+    db[j] += sign * p[j]
+  }
+}
+
+// Trains with CD on iters mini-batches drawn at random from v. With verbose
+// set the squared error of the mean-field reconstructions of the batch is
+// logged at the trainer's log interval. Returns an error, without training, if
+// there is no data or an example has the wrong length.
+func (self *SpikeSlabRBM) Train(v [][]float64, iters int, verbose bool) error {
+  rbm, tr := self.rbm, self.trainer
+  if err := rbm.checkFloats(v); err != nil {
+    return err
+  }
+  if err := checkTrainingSize(len(v), iters); err != nil {
+    return err
+  }
+  if err := tr.startSampler(len(v)); err != nil {
+    return err
+  }
+  for it := 0; it < iters; it++ {
+    dw := zeros(rbm.d, rbm.m)
+    da, db := make([]float64, rbm.d), make([]float64, rbm.m)
+    batch := make([][]float64, tr.batchSize)
+    for k := range batch {
+      batch[k] = v[tr.drawExample(len(v))]
+      neg := batch[k]
+      for t := 0; t < rbm.cdt; t++ {
+        neg = self.sampleVisible(self.sampleHidden(neg))
+      }
+      self.addFreeEnergyGradient(batch[k], 1, dw, da, db)
+      self.addFreeEnergyGradient(neg, -1, dw, da, db)
+    }
+    if tr.weightDecay != 0 {
+      for i := 0; i < rbm.d; i++ {
+        for j := 0; j < rbm.m; j++ {
+          dw[i][j] -= tr.weightDecay * float64(tr.batchSize) * rbm.weight(i, j)
+        }
+      }
+    }
+    tr.applyGradient(tr.learningRate() / float64(tr.batchSize), dw, da, db)
+    tr.steps++
+    if verbose && (it + 1) % tr.logInterval() == 0 {
+      tr.logf("Training iteration: %d, reconstruction error: %.4f\n", it + 1, self.reconstructionError(batch))
+    }
+  }
+  return nil
+}
+
+// Mean squared error of the mean-field reconstructions E[v | E[hs | v]].
+func (self *SpikeSlabRBM) reconstructionError(vs [][]float64) float64 {
+  e := 0.0
+  for _, v := range vs {
+    for i, x := range self.Reconstruct(v) {
+      e += (v[i] - x) * (v[i] - x)
+    }
+  }
+  return e / float64(len(vs))
+}
+
+// The mean-field reconstruction of v.
+func (self *SpikeSlabRBM) Reconstruct(v []float64) []float64 {
+  return self.visibleMeans(self.slabMeans(v))
+}
+
+// Spike probabilities p(h_j = 1 | v) of each example.
+func (self *SpikeSlabRBM) Transform(vs [][]float64) [][]float64 {
+  out := make([][]float64, len(vs))
+  for n, v := range vs {
+    _, out[n] = self.spikes(v)
+  }
+  return out
+}
+
+// Expected spike-times-slab E[h_j s_j | v] of each example, real-valued
+// features that keep the sign and size of the filter responses.
+func (self *SpikeSlabRBM) TransformSlab(vs [][]float64) [][]float64 {
+  out := make([][]float64, len(vs))
+  for n, v := range vs {
+    out[n] = self.slabMeans(v)
+  }
+  return out
+}
+
+// F(v) = v'Λv / 2 - a.v - sum_j log(1 + exp(b_j + (W_j.v)^2 / (2 α_j))), up
+// to a constant.
+func (self *SpikeSlabRBM) FreeEnergy(v []float64) float64 {
+  u, _ := self.spikes(v)
+  f := 0.0
+  for i, vi := range v {
+    f += self.lambda[i] * vi * vi / 2 - self.rbm.a[i] * vi
+  }
+  for j, uj := range u {
+    f -= softplus(self.rbm.b[j] + uj * uj / (2 * self.alpha[j]))
+  }
+  return f
+}
+
+// A sample after iters block Gibbs steps from a draw of p(v | h = 0).
+func (self *SpikeSlabRBM) Generate(iters int) []float64 {
+  v := self.sampleVisible(make([]float64, self.rbm.m))
+  for it := 0; it < iters; it++ {
+    v = self.sampleVisible(self.sampleHidden(v))
+  }
+  return v
+}
@@ -0,0 +1,120 @@
+package rbm
+
+import (
+  "encoding"
+  "encoding/gob"
+  "errors"
+  "io"
+  "math/rand"
+)
+
+// Everything SaveState writes: the parameters plus the state that training
+// accumulates on top of them.
+type trainingState struct {
+  Params *Params
+  Steps int
+  VelW [][]float64
+  VelA, VelB []float64
+  Particles [][]float64
+  PTChains [][]float64
+  HiddenActivity []float64
+  VisibleOffset, HiddenOffset []float64
+  Optimizer []byte
+  Sampler []byte
+  Source []byte
+}
+
+// Gradient steps taken so far, the clock of learning rate schedules.
+func (self *Trainer) Steps() int {
+  return self.steps
+}
+
+// Writes the model's parameters and the full training state (momentum
+// velocities or optimizer state, PCD and tempering chains, sparsity estimates
+// and centering offsets, the step count, the position of a shuffled Sampler in
+// its epoch and, for models seeded with WithSeed, the random stream) so that
+// an interrupted run can be resumed with LoadState. Optimizers and Samplers
+// from outside the package are saved if they implement
+// encoding.BinaryMarshaler. The options themselves are not saved.
+func (self *Trainer) SaveState(w io.Writer) error {
+  self.sync()
+  state := trainingState{
+    Params: self.model.params(),
+    Steps: self.steps,
+    VelW: self.velW, VelA: self.velA, VelB: self.velB,
+    HiddenActivity: self.hiddenActivity,
+    VisibleOffset: self.visibleOffset,
+    HiddenOffset: self.hiddenOffset,
+  }
+  switch p := self.phase.(type) {
+  case *PersistentCD:
+    state.Particles = p.particles
+  case *ParallelTempering:
+    state.PTChains = p.chains
+  }
+  if opt, ok := self.optimizer.(encoding.BinaryMarshaler); ok {
+    data, err := opt.MarshalBinary()
+    if err != nil {
+      return err
+    }
+    state.Optimizer = data
+  }
+  if s, ok := self.sampler.(encoding.BinaryMarshaler); ok {
+    data, err := s.MarshalBinary()
+    if err != nil {
+      return err
+    }
+    state.Sampler = data
+  }
+  if src := self.model.src; src != nil {
+    state.Source, _ = src.MarshalBinary()
+  }
+  return gob.NewEncoder(w).Encode(&state)
+}
+
+// Restores a state written by SaveState into self and its model, which
+// should be built with the same options as the saved ones. Training then
+// continues exactly where it left off: with a WithSeed source, the same calls
+// produce the same parameters as an uninterrupted run.
+func (self *Trainer) LoadState(r io.Reader) error {
+  var state trainingState
+  if err := gob.NewDecoder(r).Decode(&state); err != nil {
+    return err
+  }
+  if state.Params == nil {
+    return errors.New("rbm: training state has no parameters")
+  }
+  model := self.model
+  if err := model.load(state.Params); err != nil {
+    return err
+  }
+  self.Reset()
+  self.steps = state.Steps
+  self.velW, self.velA, self.velB = state.VelW, state.VelA, state.VelB
+  switch p := self.phase.(type) {
+  case *PersistentCD:
+    p.particles = state.Particles
+  case *ParallelTempering:
+    p.chains = state.PTChains
+  }
+  self.hiddenActivity = state.HiddenActivity
+  self.visibleOffset, self.hiddenOffset = state.VisibleOffset, state.HiddenOffset
+  if opt, ok := self.optimizer.(encoding.BinaryUnmarshaler); ok && state.Optimizer != nil {
+    if err := opt.UnmarshalBinary(state.Optimizer); err != nil {
+      return err
+    }
+  }
+  if s, ok := self.sampler.(encoding.BinaryUnmarshaler); ok && state.Sampler != nil {
+    if err := s.UnmarshalBinary(state.Sampler); err != nil {
+      return err
+    }
+  }
+  if state.Source != nil {
+    model.src = new(Source)
+    if err := model.src.UnmarshalBinary(state.Source); err != nil {
+      return err
+    }
+    model.r = rand.New(model.src)
+  }
+  return nil
+}
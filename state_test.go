@@ -0,0 +1,49 @@
+package rbm
+
+import (
+  "bytes"
+  "testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+  data := [][]int{{1, 0, 1, 0, 1, 0}, {0, 1, 0, 1, 0, 1}, {1, 1, 0, 0, 1, 1}}
+  // optimizers and samplers keep state, so each trainer gets its own
+  trainer := func() *Trainer {
+    return NewTrainer(New(6, 4, WithSeed(7)), WithOptimizer(NewAdam(0.9, 0.999)), WithPCD(2),
+      WithSampler(NewShuffledSampler()), WithBatchSize(2))
+  }
+  m := trainer()
+  if _, err := m.Train(data, 5, false); err != nil {
+    t.Fatal(err)
+  }
+  var buf bytes.Buffer
+  if err := m.SaveState(&buf); err != nil {
+    t.Fatal(err)
+  }
+  resumed := trainer()
+  if err := resumed.LoadState(&buf); err != nil {
+    t.Fatal(err)
+  }
+  sameModel(t, "state", resumed.Model(), m.Model())
+  // training continues exactly where it left off
+  if _, err := m.Train(data, 7, false); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := resumed.Train(data, 7, false); err != nil {
+    t.Fatal(err)
+  }
+  sameModel(t, "resumed", resumed.Model(), m.Model())
+}
+
+// The state format goes through the same checks as JSON.
+func TestStateDecodeInvalid(t *testing.T) {
+  bad := New(3, 2, WithSeed(1))
+  bad.softmaxGroups = [][]int{{0, 1}, {1, 2}}
+  var buf bytes.Buffer
+  if err := NewTrainer(bad).SaveState(&buf); err != nil {
+    t.Fatal(err)
+  }
+  if err := NewTrainer(New(3, 2, WithSeed(1))).LoadState(&buf); err == nil {
+    t.Error("decoded without error")
+  }
+}
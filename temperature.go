@@ -0,0 +1,98 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Sampling at temperature T: every unit's input (bias plus weighted sum) is
+// divided by T before the sigmoid or softmax, and Gaussian noise is scaled by
+// sqrt(T), which samples from p(v, h)^(1/T). T < 1 sharpens samples towards
+// the modes, T > 1 smooths them towards noise; T = 1 is ordinary sampling and
+// T = 0 deterministically picks the most probable state of each layer given
+// the other. Negative temperatures panic.
+
+func inverseTemperature(T float64) float64 {
+  if T < 0 {
+    panic("rbm: temperature must not be negative")
+  }
+  return 1 / T
+}
+
+// The most probable h given v: binary units on when their input is positive,
+// NReLU units at max(0, input).
+func (self *RBM) hiddenMode(v []float64) []float64 {
+  x := self.hiddenInputs(v)
+  for j := 0; j < self.m; j++ {
+    if self.dropMask != nil && !self.dropMask[j] {
+      x[j] = 0
+    } else if self.hiddenType == NReLU {
+      x[j] = math.Max(0, x[j])
+    } else if x[j] > 0 {
+      x[j] = 1
+    } else {
+      x[j] = 0
+    }
+  }
+  return x
+}
+
+// The most probable v given h: thresholded probabilities for binary units,
+// the likeliest unit of each softmax group, means for Gaussian units and
+// modes for count units.
+func (self *RBM) visibleMode(h []float64) []float64 {
+  p := self.visibleMeans(h)
+  for i := 0; i < self.d; i++ {
+    if self.inSoftmax(i) {
+      continue
+    }
+    switch self.visibleType {
+    case Binary:
+      p[i] = math.Round(p[i])
+    case Poisson, Binomial:
+      p[i] = self.countMode(p[i])
+    }
+  }
+  for _, group := range self.softmaxGroups {
+    best := group[0]
+    for _, i := range group {
+      if p[i] > p[best] {
+        best = i
+      }
+    }
+    for _, i := range group {
+      p[i] = 0
+    }
+    p[best] = 1
+  }
+  return p
+}
+
+func (self *RBM) SampleHiddenLayerAt(v []int, T float64) []int {
+  return toInts(self.sampleHiddenAt(toFloats(v), inverseTemperature(T)))
+}
+func (self *RBM) SampleVisibleLayerAt(h []int, T float64) []int {
+  return toInts(self.sampleVisibleAt(toFloats(h), inverseTemperature(T)))
+}
+func (self *RBM) SampleVisibleLayerAtFloat(h []int, T float64) []float64 {
+  return self.sampleVisibleAt(toFloats(h), inverseTemperature(T))
+}
+
+// Same as GenerateVisible with every Gibbs step at temperature T.
+func (self *RBM) GenerateVisibleAt(iters int, T float64) []int {
+  return toInts(self.GenerateVisibleAtFloat(iters, T))
+}
+func (self *RBM) GenerateVisibleAtFloat(iters int, T float64) []float64 {
+  c := self.NewGibbsChainFloat(nil)
+  c.SetTemperature(T)
+  c.Step(iters)
+  return c.VisibleFloat()
+}
+
+// Sets the temperature of the chain's subsequent steps (initially 1).
+// Changing it between calls to Step gives annealed generation schedules.
+func (self *GibbsChain) SetTemperature(T float64) {
+  self.beta = inverseTemperature(T)
+}
+func (self *GibbsChain) Temperature() float64 {
+  return 1 / self.beta
+}
@@ -0,0 +1,22 @@
+package rbm
+
+import (
+  "math"
+)
+
+// hidden layer sample from p(h | v) at inverse temperature beta
+func (self *RBM) sampleHiddenAt(v []float64, beta float64) []float64 {
+  if math.IsInf(beta, 1) {
+    return self.hiddenMode(v)
+  }
+  return self.sampleHiddenFrom(self.hiddenInputs(v), beta)
+}
+
+// visible layer sample from p(v | h) at inverse temperature beta
+func (self *RBM) sampleVisibleAt(h []float64, beta float64) []float64 {
+  if math.IsInf(beta, 1) {
+    return self.visibleMode(h)
+  }
+  p := self.visibleMeansFrom(self.visibleInputs(h), beta)
+  return self.sampleVisibleFrom(p, beta)
+}
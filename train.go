@@ -0,0 +1,629 @@
+package rbm
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "math"
+)
+
+// Estimate of the log-likelihood gradient with respect to w, a and b at v,
+// using the configured NegativePhase
+func (self *Trainer) gradient(v []float64) (dw [][]float64, da, db []float64) {
+  vSamples, hSamples := self.negativeSamples([][]float64{v})
+  dw, da, db, _ = self.model.gradientFrom(v, vSamples, hSamples)
+  return
+}
+
+// Gradient at v with the model expectations taken over the given negative
+// phase samples, or over a fresh CD chain from v if vSamples is nil. Also
+// returns the data expectation E[h | v].
+func (self *RBM) gradientFrom(v []float64, vSamples, hSamples [][]float64) (dw [][]float64, da, db, hExp []float64) {
+  hExp = self.hiddenExpectation(v)
+  if vSamples == nil {
+    vSamples, hSamples = self.sampleChain(v)
+  }
+  T := len(vSamples)
+  // visible unit bias gradient
+  da = make([]float64, self.d)
+  for i := 0; i < self.d; i++ {
+    vModelExp := 0.0
+    for t := 0; t < T; t++ {
+      vModelExp += vSamples[t][i]
+    }
+    vModelExp /= float64(T)
+    da[i] = v[i] - vModelExp
+  }
+  // hidden unit bias gradient
+  db = make([]float64, self.m)
+  for j := 0; j < self.m; j++ {
+    hModelExp := 0.0
+    for t := 0; t < T; t++ {
+      hModelExp += hSamples[t][j]
+    }
+    hModelExp /= float64(T)
+    db[j] = hExp[j] - hModelExp
+  }
+  // connection weights gradient
+  dw = make([][]float64, self.d)
+  for i := 0; i < self.d; i++ {
+    dw[i] = make([]float64, self.m)
+    for t := 0; t < T; t++ {
+      if vSamples[t][i] == 0 {
+        continue
+      }
+      vt, ht := vSamples[t][i], hSamples[t]
+      for j := 0; j < self.m; j++ {
+        dw[i][j] += vt * ht[j]
+      }
+    }
+    for j := 0; j < self.m; j++ {
+      dataExp := v[i] * hExp[j]
+      dw[i][j] = dataExp - dw[i][j] / float64(T)
+    }
+  }
+  return
+}
+
+// Folds the scaled gradient into the momentum velocities and returns them as
+// the step to take.
+func (self *Trainer) momentumStep(epsilon float64, dw [][]float64, da, db []float64) ([][]float64, []float64, []float64) {
+  model := self.model
+  if self.velW == nil {
+    self.velW = make([][]float64, model.d)
+    for i := 0; i < model.d; i++ {
+      self.velW[i] = make([]float64, model.m)
+    }
+    self.velA = make([]float64, model.d)
+    self.velB = make([]float64, model.m)
+  }
+  mu := self.momentum
+  for i := 0; i < model.d; i++ {
+    self.velA[i] = mu * self.velA[i] + epsilon * da[i]
+    for j := 0; j < model.m; j++ {
+      self.velW[i][j] = mu * self.velW[i][j] + epsilon * dw[i][j]
+    }
+  }
+  for j := 0; j < model.m; j++ {
+    self.velB[j] = mu * self.velB[j] + epsilon * db[j]
+  }
+  return self.velW, self.velA, self.velB
+}
+
+func (self *Trainer) applyGradient(epsilon float64, dw [][]float64, da, db []float64) {
+  self.sync()
+  model := self.model
+  if self.clipValue > 0 || self.clipNorm > 0 {
+    self.clipGradient(dw, da, db)
+  }
+  // the rate scales apply to the steps the optimizer computes, and to the
+  // gradients folded into the momentum, which is linear in them
+  if self.optimizer != nil {
+    self.optimizer.Step(epsilon, dw, da, db)
+    self.scaleSteps(dw, da, db)
+    epsilon = 1
+  } else {
+    self.scaleSteps(dw, da, db)
+    if self.momentum != 0 {
+      dw, da, db = self.momentumStep(epsilon, dw, da, db)
+      epsilon = 1
+    }
+  }
+  for i := 0; i < model.d; i++ {
+    model.a[i] += epsilon * da[i]
+  }
+  for j := 0; j < model.m; j++ {
+    model.b[j] += epsilon * db[j]
+  }
+  rows := model.d
+  if model.colMajor {
+    rows = model.m
+  }
+  for k := 0; k < rows; k++ {
+    model.updateRow(k, epsilon, dw)
+  }
+}
+
+// The CD gradient at v that GradientStep would apply, without applying it.
+// dW is d x m regardless of the weight layout.
+func (self *Trainer) ParameterGradients(v []int) (dW [][]float64, dA, dB []float64) {
+  return self.gradient(toFloats(v))
+}
+
+func (self *Trainer) GradientStep(v []int) error {
+  return self.GradientStepBatch([][]int{v})
+}
+func (self *Trainer) GradientStepFloat(v []float64) error {
+  return self.GradientStepBatchFloat([][]float64{v})
+}
+
+// One update with the CD gradient averaged over the mini-batch vs, which
+// must not be empty.
+func (self *Trainer) GradientStepBatch(vs [][]int) error {
+  if err := self.model.checkInts(vs); err != nil {
+    return err
+  }
+  batch := make([][]float64, len(vs))
+  for n, v := range vs {
+    batch[n] = toFloats(v)
+  }
+  return self.GradientStepBatchFloat(batch)
+}
+// Same as GradientStepBatch for real-valued visible data (Gaussian units).
+func (self *Trainer) GradientStepBatchFloat(vs [][]float64) error {
+  if len(vs) == 0 {
+    return errors.New("rbm: empty mini-batch")
+  }
+  if err := self.model.checkFloats(vs); err != nil {
+    return err
+  }
+  self.gradientStepBatch(vs)
+  return nil
+}
+
+func (self *Trainer) gradientStepBatch(vs [][]float64) {
+  self.scaledGradientStep(vs, 1)
+}
+
+// gradientStepBatch with the learning rate multiplied by scale.
+func (self *Trainer) scaledGradientStep(vs [][]float64, scale float64) {
+  self.weightedGradientStep(vs, nil, scale)
+}
+
+// scaledGradientStep with example n's gradient multiplied by weights[n], or
+// unweighted if weights is nil.
+func (self *Trainer) weightedGradientStep(vs [][]float64, weights []float64, scale float64) {
+  if len(vs) == 0 {
+    return
+  }
+  dw, da, db := self.regularizedGradient(vs, weights)
+  self.applyGradient(scale * self.learningRate(), dw, da, db)
+  self.steps++
+}
+
+// The log-likelihood gradient of weightedGradientStep, averaged over the
+// batch, with the centering, sparsity and weight decay terms.
+func (self *Trainer) regularizedGradient(vs [][]float64, weights []float64) (dw [][]float64, da, db []float64) {
+  dw, da, db, hMean := self.averageGradient(vs, weights)
+  if self.centering != 0 {
+    self.centerGradient(vs, hMean, dw, da, db)
+  }
+  self.penalize(vs, hMean, dw, db)
+  if self.centering != 0 {
+    self.uncenterGradient(dw, da, db)
+  }
+  return dw, da, db
+}
+
+// The plain log-likelihood gradient averaged over the batch vs, and the
+// mean hidden activations of the batch, for updates that combine it with
+// other terms before penalize and applyGradient.
+func (self *Trainer) averageGradient(vs [][]float64, weights []float64) (dw [][]float64, da, db, hMean []float64) {
+  model := self.model
+  // with PCD or PT all examples of the batch share one negative phase
+  negV, negH := self.negativeSamples(vs)
+  self.lastV = make([][]float64, len(vs))
+  for n, v := range vs {
+    self.lastV[n] = append([]float64(nil), v...)
+  }
+  self.lastHDelta = make([][]float64, len(vs))
+  dw, da, db, hMean = self.sumGradientsParallel(vs, weights, negV, negH, self.lastHDelta)
+  if N := float64(len(vs)); N > 1 {
+    for i := 0; i < model.d; i++ {
+      da[i] /= N
+      for j := 0; j < model.m; j++ {
+        dw[i][j] /= N
+      }
+    }
+    for j := 0; j < model.m; j++ {
+      db[j] /= N
+      hMean[j] /= N
+    }
+  }
+  return dw, da, db, hMean
+}
+
+// Adds the sparsity and weight decay terms to the gradient (dw, db) of the
+// batch vs, whose mean hidden activations are hMean.
+func (self *Trainer) penalize(vs [][]float64, hMean []float64, dw [][]float64, db []float64) {
+  model := self.model
+  if self.sparsityCost != 0 {
+    self.sparsityPenalty(vs, hMean, dw, db)
+  }
+  if self.weightDecay != 0 {
+    for i := 0; i < model.d; i++ {
+      for j := 0; j < model.m; j++ {
+        dw[i][j] -= self.weightDecay * model.weight(i, j)
+      }
+    }
+  }
+}
+
+// Updates the running estimate q of each hidden unit's mean activation with
+// the batch mean hMean and adds cost * (target - q) to the gradient of the
+// hidden biases and, scaled by the batch mean of v_i, of the weights.
+func (self *Trainer) sparsityPenalty(vs [][]float64, hMean []float64, dw [][]float64, db []float64) {
+  model := self.model
+  if self.hiddenActivity == nil {
+    self.hiddenActivity = append([]float64(nil), hMean...)
+  } else {
+    for j := 0; j < model.m; j++ {
+      self.hiddenActivity[j] = self.sparsityDecay * self.hiddenActivity[j] + (1 - self.sparsityDecay) * hMean[j]
+    }
+  }
+  penalty := make([]float64, model.m)
+  for j := 0; j < model.m; j++ {
+    penalty[j] = self.sparsityCost * (self.sparsityTarget - self.hiddenActivity[j])
+    db[j] += penalty[j]
+  }
+  for i := 0; i < model.d; i++ {
+    vMean := 0.0
+    for _, v := range vs {
+      vMean += v[i]
+    }
+    vMean /= float64(len(vs))
+    if vMean == 0 {
+      continue
+    }
+    for j := 0; j < model.m; j++ {
+      dw[i][j] += penalty[j] * vMean
+    }
+  }
+}
+
+// Runs numSamples independent CD gradient estimates on randomly drawn
+// examples of v and returns, averaged over all weights, the fraction of
+// estimates whose sign agrees with the mean gradient. Near 1.0 the gradient
+// direction is stable; near 0.5 the estimates are mostly noise.
+func (self *Trainer) GradientSignConsistency(v [][]int, numSamples int) float64 {
+  model := self.model
+  if len(v) == 0 || numSamples <= 0 {
+    return 0
+  }
+  N := len(v)
+  dws := make([][][]float64, numSamples)
+  mean := make([][]float64, model.d)
+  for i := 0; i < model.d; i++ {
+    mean[i] = make([]float64, model.m)
+  }
+  for s := 0; s < numSamples; s++ {
+    n := int(uniform(model.r) * float64(N))
+    dws[s], _, _ = self.gradient(toFloats(v[n]))
+    for i := 0; i < model.d; i++ {
+      for j := 0; j < model.m; j++ {
+        mean[i][j] += dws[s][i][j] / float64(numSamples)
+      }
+    }
+  }
+  agree := 0
+  for s := 0; s < numSamples; s++ {
+    for i := 0; i < model.d; i++ {
+      for j := 0; j < model.m; j++ {
+        if sign(dws[s][i][j]) == sign(mean[i][j]) {
+          agree++
+        }
+      }
+    }
+  }
+  return float64(agree) / float64(numSamples * model.d * model.m)
+}
+
+// Taylor-expansion pruning criterion |w_ij| * |dF/dw_ij| with the gradient
+// approximated by (hExp_j - hModelExp_j) * v_i, averaged over the examples of
+// the last gradient step. All zero before any training.
+func (self *Trainer) ConnectionImportance() [][]float64 {
+  model := self.model
+  imp := make([][]float64, model.d)
+  for i := 0; i < model.d; i++ {
+    imp[i] = make([]float64, model.m)
+  }
+  N := len(self.lastV)
+  for n := 0; n < N; n++ {
+    v, hDelta := self.lastV[n], self.lastHDelta[n]
+    for i := 0; i < model.d; i++ {
+      if v[i] == 0 {
+        continue
+      }
+      for j := 0; j < model.m; j++ {
+        imp[i][j] += math.Abs(hDelta[j] * v[i])
+      }
+    }
+  }
+  for i := 0; i < model.d; i++ {
+    for j := 0; j < model.m; j++ {
+      imp[i][j] *= math.Abs(model.weight(i, j)) / math.Max(float64(N), 1)
+    }
+  }
+  return imp
+}
+
+// Trains for iters mini-batches drawn at random from v, returning the
+// learning curve of the run. Returns an error, before taking any step, if v
+// is empty or an example doesn't have one value per visible unit.
+func (self *Trainer) Train(v [][]int, iters int, verbose bool) (History, error) {
+  return self.TrainContext(context.Background(), v, iters, verbose)
+}
+// Same as Train for real-valued visible data (Gaussian units).
+func (self *Trainer) TrainFloat(v [][]float64, iters int, verbose bool) (History, error) {
+  return self.TrainFloatContext(context.Background(), v, iters, verbose)
+}
+
+// Same as Train, but stops between gradient steps once ctx is cancelled or
+// its deadline passes, returning ctx.Err(). The model keeps the updates made
+// so far, and the history covers them.
+func (self *Trainer) TrainContext(ctx context.Context, v [][]int, iters int, verbose bool) (History, error) {
+  if err := self.model.checkInts(v); err != nil {
+    return History{}, err
+  }
+  return self.train(ctx, len(v), func(n int) []float64 { return toFloats(v[n]) }, iters, verbose)
+}
+// Same as TrainContext for real-valued visible data (Gaussian units).
+func (self *Trainer) TrainFloatContext(ctx context.Context, v [][]float64, iters int, verbose bool) (History, error) {
+  if err := self.model.checkFloats(v); err != nil {
+    return History{}, err
+  }
+  return self.train(ctx, len(v), func(n int) []float64 { return v[n] }, iters, verbose)
+}
+
+// Train over N examples, converting each drawn example with example(n) so
+// integer datasets never have to be copied in full. With binary visibles the
+// verbose progress lines and the history include an estimate of the mean
+// pseudo-likelihood of (up to) the first 100 examples, see
+// monitorPseudoLikelihood.
+func (self *Trainer) train(ctx context.Context, N int, example func(n int) []float64, iters int, verbose bool) (hist History, err error) {
+  return self.trainWeighted(ctx, N, example, nil, iters, verbose)
+}
+// train with the gradient of example n multiplied by weight(n), or
+// unweighted if weight is nil
+func (self *Trainer) trainWeighted(ctx context.Context, N int, example func(n int) []float64, weight func(n int) float64, iters int, verbose bool) (hist History, err error) {
+  if err := checkTrainingSize(N, iters); err != nil {
+    return hist, err
+  }
+  if err := self.startSampler(N); err != nil {
+    return hist, err
+  }
+  if es := self.earlyStop; es != nil {
+    es.begin()
+    defer es.end(self.model)
+  }
+  pm := newProgressMeter(iters)
+  for it := 0; it < iters; it++ {
+    if err := ctx.Err(); err != nil {
+      return hist, err
+    }
+    batch := make([][]float64, self.batchSize)
+    var weights []float64
+    if weight != nil {
+      weights = make([]float64, self.batchSize)
+    }
+    for k := range batch {
+      n := self.drawExample(N)
+      batch[k] = example(n)
+      if weight != nil {
+        weights[k] = weight(n)
+      }
+    }
+    self.weightedGradientStep(batch, weights, 1)
+    if due := (it + 1) % self.logInterval() == 0; due || it + 1 == iters {
+      p := self.progressAt(pm, it + 1, 0, batch)
+      pl := hist.record(self, p, N, example)
+      if verbose && due {
+        if self.model.visibleType == Binary {
+          self.logf("Training iteration: %v, pseudo-likelihood: %.4f\n", p, pl)
+        } else {
+          self.logf("Training iteration: %v\n", p)
+        }
+      }
+    }
+    self.reportProgress(pm, it + 1, 0, batch)
+    if self.callbacks != nil && !self.runCallbacks(it, 0, batch) {
+      return hist, nil
+    }
+    if cp := self.checkpoint; cp != nil && (it + 1) % cp.every == 0 {
+      self.saveCheckpoint(it + 1)
+    }
+    if es := self.earlyStop; es != nil && (it + 1) % es.every == 0 && !es.check(self.model) {
+      return hist, nil
+    }
+  }
+  return hist, nil
+}
+
+// Online training: one pass over vs in order, batchSize examples per
+// gradient step (the last batch may be smaller). Momentum, persistent chains
+// and the schedule's step count carry over between calls, so a stream can be
+// fed in pieces of any size without holding it all in memory. Returns an
+// error, without training, if an example has the wrong length.
+func (self *Trainer) PartialFit(vs [][]int) error {
+  if err := self.model.checkInts(vs); err != nil {
+    return err
+  }
+  for start := 0; start < len(vs); start += self.batchSize {
+    end := start + self.batchSize
+    if end > len(vs) {
+      end = len(vs)
+    }
+    batch := make([][]float64, end - start)
+    for k := range batch {
+      batch[k] = toFloats(vs[start + k])
+    }
+    self.gradientStepBatch(batch)
+  }
+  return nil
+}
+// Same as PartialFit for real-valued visible data (Gaussian units).
+func (self *Trainer) PartialFitFloat(vs [][]float64) error {
+  if err := self.model.checkFloats(vs); err != nil {
+    return err
+  }
+  for start := 0; start < len(vs); start += self.batchSize {
+    end := start + self.batchSize
+    if end > len(vs) {
+      end = len(vs)
+    }
+    self.gradientStepBatch(vs[start:end])
+  }
+  return nil
+}
+
+// Trains on examples received from ch until it is closed or ctx is done,
+// taking a gradient step every batchSize examples. Returns the number of
+// examples used and ctx.Err() if ctx ended the stream, or an error at the
+// first example of the wrong length (the rest of its batch is dropped).
+func (self *Trainer) FitStream(ctx context.Context, ch <-chan []int) (int, error) {
+  n := 0
+  batch := make([][]float64, 0, self.batchSize)
+  for {
+    select {
+    case <-ctx.Done():
+      return n, ctx.Err()
+    case v, ok := <-ch:
+      if !ok {
+        if len(batch) > 0 {
+          self.gradientStepBatch(batch)
+        }
+        return n, nil
+      }
+      if len(v) != self.model.d {
+        return n, fmt.Errorf("rbm: example %d has length %d, want %d", n, len(v), self.model.d)
+      }
+      n++
+      if batch = append(batch, toFloats(v)); len(batch) == self.batchSize {
+        self.gradientStepBatch(batch)
+        batch = batch[:0]
+      }
+    }
+  }
+}
+
+// Epoch-based training: each epoch visits the examples in a fresh random
+// order, batchSize at a time (the last batch may be smaller), so every
+// example contributes exactly once per epoch. With a Sampler (WithSampler)
+// an epoch is instead len(v) examples drawn from it. Returns the learning
+// curve of the run, or an error, without training, if an example has the
+// wrong length or the sampler rejects the data.
+func (self *Trainer) TrainEpochs(v [][]int, epochs int, verbose bool) (History, error) {
+  if err := self.model.checkInts(v); err != nil {
+    return History{}, err
+  }
+  return self.trainEpochs(len(v), func(n int) []float64 { return toFloats(v[n]) }, epochs, verbose)
+}
+// Same as TrainEpochs for real-valued visible data (Gaussian units).
+func (self *Trainer) TrainEpochsFloat(v [][]float64, epochs int, verbose bool) (History, error) {
+  if err := self.model.checkFloats(v); err != nil {
+    return History{}, err
+  }
+  return self.trainEpochs(len(v), func(n int) []float64 { return v[n] }, epochs, verbose)
+}
+
+func (self *Trainer) trainEpochs(N int, example func(n int) []float64, epochs int, verbose bool) (hist History, err error) {
+  if N == 0 {
+    return
+  }
+  if err = self.startSampler(N); err != nil {
+    return
+  }
+  if es := self.earlyStop; es != nil {
+    es.begin()
+    defer es.end(self.model)
+  }
+  it := 0
+  var batch [][]float64
+  pm := newProgressMeter(epochs * ((N + self.batchSize - 1) / self.batchSize))
+  for epoch := 0; epoch < epochs; epoch++ {
+    order := self.epochOrder(N)
+    for start := 0; start < N; start += self.batchSize {
+      end := start + self.batchSize
+      if end > N {
+        end = N
+      }
+      batch = batch[:0]
+      for _, n := range order[start:end] {
+        batch = append(batch, example(n))
+      }
+      self.gradientStepBatch(batch)
+      self.reportProgress(pm, it + 1, epoch, batch)
+      if self.callbacks != nil && !self.runCallbacks(it, epoch, batch) {
+        return
+      }
+      if cp := self.checkpoint; cp != nil && (it + 1) % cp.every == 0 {
+        self.saveCheckpoint(it + 1)
+      }
+      it++
+    }
+    p := self.progressAt(pm, it, epoch + 1, batch)
+    pl := hist.record(self, p, N, example)
+    if verbose {
+      if self.model.visibleType == Binary {
+        self.logf("Training epoch: %d, iteration %v, pseudo-likelihood: %.4f\n", epoch + 1, p, pl)
+      } else {
+        self.logf("Training epoch: %d, iteration %v\n", epoch + 1, p)
+      }
+    }
+    if self.epochCallbacks != nil && !self.runEpochCallbacks(it, epoch + 1, batch) {
+      return
+    }
+    if es := self.earlyStop; es != nil && (epoch + 1) % es.every == 0 && !es.check(self.model) {
+      return
+    }
+  }
+  return
+}
+
+// The examples of one epoch over N: a random permutation, or N draws from
+// the sampler if there is one.
+func (self *Trainer) epochOrder(N int) []int {
+  if self.sampler == nil {
+    return perm(self.model.r, N)
+  }
+  order := make([]int, N)
+  for k := range order {
+    order[k] = self.drawExample(N)
+  }
+  return order
+}
+
+// Largest mini-batch whose training buffers fit in targetMemoryMB: the batch
+// itself plus its hidden samples (N*(d+m) float64s), the CD chains
+// (N*cdt*(d+m) float64s), the gradient buffers for w, a and b, the momentum
+// velocities or the optimizer state (one more such buffer for Momentum,
+// AdaGrad and RMSProp, two for Adam; optimizers from outside the package
+// count as none) and any persistent PCD or tempering chains. Returns 0 if
+// even the fixed buffers don't fit.
+func (self *Trainer) OptimalBatchSize(targetMemoryMB float64) int {
+  d, m := self.model.d, self.model.m
+  floatBytes := 8.0
+  units := float64(d + m)
+  params := floatBytes * float64(d * m + d + m)
+  buffers := 1
+  if self.optimizer != nil {
+    buffers += optimizerBuffers(self.optimizer)
+  } else if self.momentum != 0 {
+    buffers++
+  }
+  fixed := params * float64(buffers)
+  fixed += floatBytes * units * float64(phaseChains(self.phase))
+  perExample := floatBytes * units * float64(1 + self.model.cdt)
+  budget := targetMemoryMB * 1024 * 1024 - fixed
+  if budget < perExample {
+    return 0
+  }
+  return int(budget / perExample)
+}
+
+// Estimate of the mean log pseudo-likelihood of (up to) the first 100
+// examples for monitoring at iteration it: example n contributes
+// d log p(v_i | v_-i) for the single unit i = (n + it) mod d, which is
+// unbiased over the choice of unit and costs one pass over the weights
+// instead of d.
+func (self *RBM) monitorPseudoLikelihood(N int, example func(n int) []float64, it int) float64 {
+  if N > 100 {
+    N = 100
+  }
+  pl := 0.0
+  for n := 0; n < N; n++ {
+    v := example(n)
+    pl += float64(self.d) * self.logConditional(v, self.hiddenInputs(v), (n + it) % self.d)
+  }
+  return pl / float64(N)
+}
@@ -0,0 +1,320 @@
+package rbm
+
+import (
+  "fmt"
+  "math"
+)
+
+const defaultLearningRate = 0.05
+
+// Fits an RBM to data. The model only computes energies and probabilities
+// and samples; the Trainer holds everything training adds on top of that,
+// the configuration set with TrainOptions (learning rate and schedule, batch
+// size, optimizer, regularization, negative phase algorithm, sampler,
+// callbacks, ...) and the state it builds up (step count, momentum or
+// optimizer state, chains, running estimates), and runs the training loops.
+// A model can have several trainers, one per stage of a run say, but only
+// one may train it at a time.
+type Trainer struct {
+  model *RBM
+  version int // of the model parameters the state belongs to
+  phase NegativePhase // nil for CD
+  epsilon float64 // learning rate
+  schedule Schedule
+  steps int       // gradient steps taken, for the schedule
+  batchSize int   // examples per gradient step in Train
+  momentum float64
+  optimizer Optimizer
+  clipValue, clipNorm float64 // gradient clipping, 0 when off
+  weightDecay float64 // L2 penalty on w
+  sparsityTarget, sparsityCost, sparsityDecay float64
+  hiddenActivity []float64 // running mean activation of each hidden unit
+  centering float64 // offset update rate of the centering trick
+  visibleOffset, hiddenOffset []float64
+  velW [][]float64 // momentum velocities for w (d x m), a and b
+  velA []float64
+  velB []float64
+  dropout float64 // hidden dropout rate
+  sampler Sampler // of the training examples, nil for uniform draws
+  rates *rateScales // per parameter group learning rate factors, nil for none
+  callbacks []callback
+  epochCallbacks []Callback
+  earlyStop *earlyStopping
+  checkpoint *checkpointer
+  logger Logger // verbose training output, stdout if nil
+  logEvery int
+  progress *progressReporter
+  // data and hidden gradient (hExp - hModelExp) of the last gradient step
+  lastV [][]float64
+  lastHDelta [][]float64
+}
+
+// Configures a Trainer at construction (see NewTrainer) or later with
+// SetOptions.
+type TrainOption func(*Trainer)
+
+// A trainer of model configured by opts: CD (WithNegativePhase), learning
+// rate 0.05 (WithLearningRate), batch size 1 (WithBatchSize), plain SGD
+// steps and so on, see TrainOption. Training draws from the model's random
+// source.
+//
+//   t := rbm.NewTrainer(m, rbm.WithLearningRate(0.1), rbm.WithPCD(100))
+//   hist, err := t.Train(data, 10000, true)
+func NewTrainer(model *RBM, opts ...TrainOption) *Trainer {
+  self := &Trainer{model: model, version: model.version}
+  self.epsilon = defaultLearningRate
+  self.batchSize = 1
+  self.SetOptions(opts...)
+  return self
+}
+
+// Changes the configuration of an existing trainer, e.g. to use a different
+// learning rate or weight decay for another training run.
+func (self *Trainer) SetOptions(opts ...TrainOption) {
+  for _, opt := range opts {
+    opt(self)
+  }
+}
+
+// The model being trained.
+func (self *Trainer) Model() *RBM {
+  return self.model
+}
+
+// Examples per gradient step, see WithBatchSize.
+func (self *Trainer) BatchSize() int {
+  return self.batchSize
+}
+
+// Discards the training state tied to the model's parameters: momentum
+// velocities, optimizer state, negative phase chains, sparsity and centering
+// estimates. This happens by itself at the next step after the model's
+// parameters are replaced (Unmarshal, ReadFrom, CopyFrom, early stopping).
+// The step count is kept.
+func (self *Trainer) Reset() {
+  self.velW, self.velA, self.velB = nil, nil, nil
+  if self.optimizer != nil {
+    self.optimizer.Reset()
+  }
+  if self.phase != nil {
+    self.phase.Reset()
+  }
+  self.hiddenActivity = nil
+  self.visibleOffset, self.hiddenOffset = nil, nil
+  self.lastV, self.lastHDelta = nil, nil
+  self.version = self.model.version
+}
+
+// Resets the state if the model's parameters were replaced since it was
+// built up.
+func (self *Trainer) sync() {
+  if self.version != self.model.version {
+    self.Reset()
+  }
+}
+
+// The negative phase algorithm of gradient training: where the samples of
+// the gradient's model expectations come from. The model provides the
+// energies, conditionals and samplers; a NegativePhase decides which chains
+// to run with them and keeps their state, so a new algorithm only needs to
+// implement this interface. ContrastiveDivergence, PersistentCD and
+// ParallelTempering are provided; set one with WithNegativePhase.
+type NegativePhase interface {
+  // Joint samples (vs[k], hs[k]) of the model for the gradient step on
+  // batch, shared by all of its examples, or nil, nil to have each example
+  // run its own cdt step chain from itself.
+  Samples(model *RBM, batch [][]float64) (vs, hs [][]float64)
+  // Discards any chain state, as happens whenever the model's parameters
+  // are replaced.
+  Reset()
+}
+
+// Trains with p, or with ContrastiveDivergence if p is nil. A NegativePhase
+// keeps chain state, so trainers shouldn't share one (use WithPCD and
+// WithParallelTempering for options shared by several trainers, e.g. those
+// of the layers of a DBN); any state p has is discarded.
+func WithNegativePhase(p NegativePhase) TrainOption {
+  return func(self *Trainer) {
+    if p != nil {
+      p.Reset()
+    }
+    self.phase = p
+  }
+}
+
+// Persistent contrastive divergence: instead of restarting the negative phase
+// chain from the data at every step, keep numParticles fantasy particles that
+// are advanced by cdt Gibbs steps per update. numParticles <= 0 switches back
+// to plain CD. Either way any existing chains are discarded.
+func WithPCD(numParticles int) TrainOption {
+  return func(self *Trainer) {
+    // chains of its own for every trainer the option is applied to
+    self.phase = nil
+    if numParticles > 0 {
+      self.phase = NewPCD(numParticles)
+    }
+  }
+}
+
+// Parallel tempering: the negative phase comes from the T = 1 chain of
+// numChains Gibbs chains at inverse temperatures 1, 1 - 1/numChains, ...,
+// 1/numChains. Each update advances every chain by cdt steps and then
+// proposes swapping the states of neighbouring temperatures, which lets the
+// T = 1 chain escape modes that plain CD or PCD chains get stuck in.
+// numChains <= 1 switches back to plain CD.
+func WithParallelTempering(numChains int) TrainOption {
+  return func(self *Trainer) {
+    self.phase = nil
+    if numChains > 1 {
+      self.phase = NewParallelTempering(numChains)
+    }
+  }
+}
+
+func (self *Trainer) negativeSamples(data [][]float64) (vs, hs [][]float64) {
+  self.sync()
+  if self.phase == nil {
+    return nil, nil
+  }
+  return self.phase.Samples(self.model, data)
+}
+
+// The two halves of a Gibbs step on real-valued states, the sampling
+// primitives for NegativePhase implementations: a sample of p(h | v), recorded in
+// the activation history as by SampleHiddenLayer, and one of p(v | h).
+func (self *RBM) SampleHiddenFloat(v []float64) []float64 {
+  return self.sampleHidden(v)
+}
+func (self *RBM) SampleVisibleFloat(h []float64) []float64 {
+  return self.sampleVisible(h)
+}
+
+// Contrastive divergence (CD-k, k the model's cdt): a fresh chain from each
+// example, the default.
+type ContrastiveDivergence struct{}
+
+func (ContrastiveDivergence) Samples(model *RBM, batch [][]float64) (vs, hs [][]float64) {
+  return nil, nil
+}
+func (ContrastiveDivergence) Reset() {}
+
+// Persistent contrastive divergence, see WithPCD.
+type PersistentCD struct {
+  numParticles int
+  particles [][]float64
+}
+
+// PCD with numParticles fantasy particles. Panics unless numParticles is
+// positive.
+func NewPCD(numParticles int) *PersistentCD {
+  if numParticles < 1 {
+    panic(fmt.Sprintf("rbm: PCD needs at least one particle, got %d", numParticles))
+  }
+  return &PersistentCD{numParticles: numParticles}
+}
+
+// Advances the fantasy particles by cdt Gibbs steps and returns their
+// visible states with a hidden sample for each. The particles are seeded from
+// batch the first time round.
+func (self *PersistentCD) Samples(model *RBM, batch [][]float64) (vs, hs [][]float64) {
+  if self.particles == nil {
+    self.particles = make([][]float64, self.numParticles)
+    for k := range self.particles {
+      self.particles[k] = append([]float64(nil), batch[k % len(batch)]...)
+    }
+  }
+  hs = make([][]float64, self.numParticles)
+  for k, v := range self.particles {
+    for t := 0; t < model.cdt; t++ {
+      v = model.sampleVisible(model.sampleHidden(v))
+    }
+    self.particles[k] = v
+    hs[k] = model.sampleHidden(v)
+  }
+  return self.particles, hs
+}
+
+func (self *PersistentCD) Reset() {
+  self.particles = nil
+}
+
+// Parallel tempering, see WithParallelTempering.
+type ParallelTempering struct {
+  betas []float64 // inverse temperatures, betas[0] = 1
+  chains [][]float64
+}
+
+// Tempering over numChains inverse temperatures 1, 1 - 1/numChains, ...,
+// 1/numChains. Panics unless there are at least two chains.
+func NewParallelTempering(numChains int) *ParallelTempering {
+  if numChains < 2 {
+    panic(fmt.Sprintf("rbm: parallel tempering needs at least two chains, got %d", numChains))
+  }
+  betas := make([]float64, numChains)
+  for k := range betas {
+    betas[k] = 1 - float64(k) / float64(numChains)
+  }
+  return &ParallelTempering{betas: betas}
+}
+
+// Advances every tempered chain by cdt Gibbs steps, then proposes swaps
+// between neighbouring temperatures. Returns the state of the T = 1 chain.
+// The chains are seeded from batch the first time round.
+func (self *ParallelTempering) Samples(model *RBM, batch [][]float64) (vs, hs [][]float64) {
+  K := len(self.betas)
+  if self.chains == nil {
+    self.chains = make([][]float64, K)
+    for k := range self.chains {
+      self.chains[k] = append([]float64(nil), batch[k % len(batch)]...)
+    }
+  }
+  chains := self.chains
+  hiddens := make([][]float64, K)
+  for k, beta := range self.betas {
+    v := chains[k]
+    for t := 0; t < model.cdt; t++ {
+      v = model.sampleVisibleAt(model.sampleHiddenAt(v, beta), beta)
+    }
+    chains[k] = v
+    hiddens[k] = model.sampleHiddenAt(v, beta)
+  }
+  // accept a swap of x_k and x_k+1 with probability
+  // min(1, exp((beta_k - beta_k+1) * (E(x_k) - E(x_k+1))))
+  for k := 0; k + 1 < K; k++ {
+    e0 := model.energy(chains[k], hiddens[k])
+    e1 := model.energy(chains[k + 1], hiddens[k + 1])
+    logRatio := (self.betas[k] - self.betas[k + 1]) * (e0 - e1)
+    if logRatio >= 0 || uniform(model.r) < math.Exp(logRatio) {
+      chains[k], chains[k + 1] = chains[k + 1], chains[k]
+      hiddens[k], hiddens[k + 1] = hiddens[k + 1], hiddens[k]
+    }
+  }
+  return [][]float64{chains[0]}, [][]float64{hiddens[0]}
+}
+
+func (self *ParallelTempering) Reset() {
+  self.chains = nil
+}
+
+// A copy of p with its own chain state, for Clone. Negative phases other
+// than the package's own are shared.
+func clonePhase(p NegativePhase) NegativePhase {
+  switch p := p.(type) {
+  case *PersistentCD:
+    return &PersistentCD{numParticles: p.numParticles, particles: copyMatrix(p.particles)}
+  case *ParallelTempering:
+    return &ParallelTempering{betas: copyVector(p.betas), chains: copyMatrix(p.chains)}
+  }
+  return p
+}
+
+// Number of persistent chains p keeps.
+func phaseChains(p NegativePhase) int {
+  switch p := p.(type) {
+  case *PersistentCD:
+    return p.numParticles
+  case *ParallelTempering:
+    return len(p.betas)
+  }
+  return 0
+}
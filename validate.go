@@ -0,0 +1,67 @@
+package rbm
+
+import (
+  "errors"
+  "fmt"
+)
+
+// Input validation. The training methods check their data up front and
+// return an error for examples of the wrong length; the inference methods
+// (sampling, expectations, reconstructions, ...) panic with a descriptive
+// message instead, as mistakes there are programming errors, like indexing
+// a slice out of range. Constructors panic on invalid sizes.
+
+// Panics unless numVisible, numHidden and cdt are all positive.
+func checkModelSize(numVisible, numHidden, cdt int) {
+  if numVisible < 1 || numHidden < 1 {
+    panic(fmt.Sprintf("rbm: invalid dimensions %d x %d", numVisible, numHidden))
+  }
+  if cdt < 1 {
+    panic(fmt.Sprintf("rbm: cdt must be at least 1, got %d", cdt))
+  }
+}
+
+// Panics unless a vector of the layer what has length n, want.
+func checkLength(what string, n, want int) {
+  if n != want {
+    panic(fmt.Sprintf("rbm: %s vector has length %d, want %d", what, n, want))
+  }
+}
+
+// Checks that the N examples, whose lengths are given by length, all have
+// one value per visible unit.
+func (self *RBM) checkExamples(N int, length func(n int) int) error {
+  for n := 0; n < N; n++ {
+    if l := length(n); l != self.d {
+      return fmt.Errorf("rbm: example %d has length %d, want %d", n, l, self.d)
+    }
+  }
+  return nil
+}
+
+func (self *RBM) checkInts(v [][]int) error {
+  return self.checkExamples(len(v), func(n int) int { return len(v[n]) })
+}
+func (self *RBM) checkFloats(v [][]float64) error {
+  return self.checkExamples(len(v), func(n int) int { return len(v[n]) })
+}
+
+// Same as checkInts for sparse examples given as lists of active units.
+func (self *RBM) checkSparse(v [][]int) error {
+  for n, active := range v {
+    for _, i := range active {
+      if i < 0 || i >= self.d {
+        return fmt.Errorf("rbm: example %d has active unit %d, want 0 <= unit < %d", n, i, self.d)
+      }
+    }
+  }
+  return nil
+}
+
+// Training data for a run of iters random mini-batches must not be empty.
+func checkTrainingSize(N, iters int) error {
+  if N == 0 && iters > 0 {
+    return errors.New("rbm: no training examples")
+  }
+  return nil
+}
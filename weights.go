@@ -0,0 +1,81 @@
+package rbm
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "math"
+)
+
+// Per-example weights: the gradient of example n is multiplied by its weight
+// w_n, so the model follows the gradient of the weighted log-likelihood
+// sum_n w_n log p(v_n), e.g. to upweight rare but important examples or to
+// correct for a biased sample with importance weights, as in boosting. A
+// weight of 2 counts an example twice, 0 ignores it; a batch's gradient is
+// still divided by its size, so the weights also scale the step size.
+
+// Same as Train with example n weighted by weights[n]. Returns an error,
+// before taking any step, if the number of weights doesn't match the
+// examples or a weight is negative or not finite.
+func (self *Trainer) TrainWeighted(v [][]int, weights []float64, iters int, verbose bool) (History, error) {
+  return self.TrainWeightedContext(context.Background(), v, weights, iters, verbose)
+}
+// Same as TrainWeighted for real-valued visible data.
+func (self *Trainer) TrainWeightedFloat(v [][]float64, weights []float64, iters int, verbose bool) (History, error) {
+  if err := self.model.checkFloats(v); err != nil {
+    return History{}, err
+  }
+  if err := checkWeights(weights, len(v)); err != nil {
+    return History{}, err
+  }
+  return self.trainWeighted(context.Background(), len(v), func(n int) []float64 { return v[n] }, func(n int) float64 { return weights[n] }, iters, verbose)
+}
+// Same as TrainWeighted, stopping once ctx is done, see TrainContext.
+func (self *Trainer) TrainWeightedContext(ctx context.Context, v [][]int, weights []float64, iters int, verbose bool) (History, error) {
+  if err := self.model.checkInts(v); err != nil {
+    return History{}, err
+  }
+  if err := checkWeights(weights, len(v)); err != nil {
+    return History{}, err
+  }
+  return self.trainWeighted(ctx, len(v), func(n int) []float64 { return toFloats(v[n]) }, func(n int) float64 { return weights[n] }, iters, verbose)
+}
+
+// Same as GradientStepBatch with example n's gradient weighted by
+// weights[n].
+func (self *Trainer) GradientStepWeighted(vs [][]int, weights []float64) error {
+  if err := self.model.checkInts(vs); err != nil {
+    return err
+  }
+  batch := make([][]float64, len(vs))
+  for n, v := range vs {
+    batch[n] = toFloats(v)
+  }
+  return self.GradientStepWeightedFloat(batch, weights)
+}
+// Same as GradientStepWeighted for real-valued visible data.
+func (self *Trainer) GradientStepWeightedFloat(vs [][]float64, weights []float64) error {
+  if len(vs) == 0 {
+    return errors.New("rbm: empty mini-batch")
+  }
+  if err := self.model.checkFloats(vs); err != nil {
+    return err
+  }
+  if err := checkWeights(weights, len(vs)); err != nil {
+    return err
+  }
+  self.weightedGradientStep(vs, weights, 1)
+  return nil
+}
+
+func checkWeights(weights []float64, N int) error {
+  if len(weights) != N {
+    return fmt.Errorf("rbm: %d weights for %d examples", len(weights), N)
+  }
+  for n, w := range weights {
+    if w < 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+      return fmt.Errorf("rbm: example %d has weight %g, want a finite nonnegative weight", n, w)
+    }
+  }
+  return nil
+}
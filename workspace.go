@@ -0,0 +1,92 @@
+package rbm
+
+import (
+  "math"
+)
+
+// Reusable buffers for sampling from one model without allocating on every
+// call, for long-running samplers and serving loops. The slices a Workspace
+// returns are its own and are overwritten by its next call, so copy anything
+// that should outlive it. A Workspace isn't safe for concurrent use; give
+// each goroutine its own (and its own Fork of the model).
+type Workspace struct {
+  rbm *RBM
+  v, h []float64  // float copies of the caller's layer
+  hx, vx []float64 // sampled layers
+  hOut, vOut []int
+  chain *chainBuffers
+  vs, hs [][]int
+}
+
+// A Workspace for sampling from self. It tracks later changes to the
+// parameters and the CD steps.
+func (self *RBM) NewWorkspace() *Workspace {
+  return &Workspace{
+    rbm: self,
+    v: make([]float64, self.d),
+    h: make([]float64, self.m),
+    hx: make([]float64, self.m),
+    vx: make([]float64, self.d),
+    hOut: make([]int, self.m),
+    vOut: make([]int, self.d),
+    chain: self.newChainBuffers(),
+    vs: zerosInt(self.cdt, self.d),
+    hs: zerosInt(self.cdt, self.m),
+  }
+}
+
+// Same as RBM.SampleHiddenLayer.
+func (self *Workspace) SampleHiddenLayer(v []int) []int {
+  checkLength("visible", len(v), self.rbm.d)
+  intsToFloats(self.v, v)
+  return floatsToInts(self.hOut, self.rbm.sampleHiddenInto(self.hx, self.v))
+}
+// Same as RBM.SampleVisibleLayer.
+func (self *Workspace) SampleVisibleLayer(h []int) []int {
+  checkLength("hidden", len(h), self.rbm.m)
+  intsToFloats(self.h, h)
+  return floatsToInts(self.vOut, self.rbm.sampleVisibleInto(self.vx, self.h))
+}
+// Same as RBM.SampleVisibleLayerFloat.
+func (self *Workspace) SampleVisibleLayerFloat(h []int) []float64 {
+  checkLength("hidden", len(h), self.rbm.m)
+  intsToFloats(self.h, h)
+  return self.rbm.sampleVisibleInto(self.vx, self.h)
+}
+// Same as RBM.SampleModel.
+func (self *Workspace) SampleModel(v []int) (vs, hs [][]int) {
+  checkLength("visible", len(v), self.rbm.d)
+  intsToFloats(self.v, v)
+  if len(self.vs) != self.rbm.cdt {
+    self.chain = self.rbm.newChainBuffers()
+    self.vs, self.hs = zerosInt(self.rbm.cdt, self.rbm.d), zerosInt(self.rbm.cdt, self.rbm.m)
+  }
+  fvs, fhs := self.rbm.sampleChainInto(self.chain, self.v)
+  for t := range fvs {
+    floatsToInts(self.vs[t], fvs[t])
+    floatsToInts(self.hs[t], fhs[t])
+  }
+  return self.vs, self.hs
+}
+
+// toFloats and toInts writing into dst
+func intsToFloats(dst []float64, v []int) []float64 {
+  for i, vi := range v {
+    dst[i] = float64(vi)
+  }
+  return dst
+}
+func floatsToInts(dst []int, f []float64) []int {
+  for i, fi := range f {
+    dst[i] = int(math.Round(fi))
+  }
+  return dst
+}
+
+func zerosInt(rows, cols int) [][]int {
+  x := make([][]int, rows)
+  for k := range x {
+    x[k] = make([]int, cols)
+  }
+  return x
+}